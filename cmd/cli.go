@@ -20,6 +20,7 @@ import (
 	"strings"
 
 	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/source"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -36,6 +37,8 @@ type Cli struct {
 	viper       *viper.Viper
 	HelmEnv     *helmcli.EnvSettings
 	Log         *logrus.Logger
+	// sources resolves remote sources, it is created on first use
+	sources *source.Resolver
 }
 
 // NewCli creates a
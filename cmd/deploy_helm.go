@@ -21,6 +21,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bedag/kusible/pkg/playbook/config"
 	"github.com/bedag/kusible/pkg/printer"
 	helmutil "github.com/bedag/kusible/pkg/wrapper/helm"
 	"github.com/sirupsen/logrus"
@@ -30,9 +31,9 @@ import (
 
 func newDeployHelmCmd(c *Cli) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:                   "helm [playbook]",
+		Use:                   "helm [playbook...]",
 		Short:                 "Use helm to deploy an application",
-		Args:                  cobra.ExactArgs(1),
+		Args:                  cobra.MinimumNArgs(1),
 		TraverseChildren:      true,
 		DisableFlagsInUseLine: true,
 		RunE:                  c.wrap(runDeployHelm),
@@ -44,14 +45,14 @@ func newDeployHelmCmd(c *Cli) *cobra.Command {
 }
 
 func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
-	playbookFile := args[0]
+	playbookFiles := args
 
 	inv, err := getInventoryWithKubeconfig(c)
 	if err != nil {
 		return err
 	}
 
-	playbookSet, err := loadPlaybooks(c, playbookFile)
+	playbookSet, err := loadPlaybooks(c, playbookFiles)
 	if err != nil {
 		return err
 	}
@@ -59,11 +60,26 @@ func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
 	helmOptions := helmutil.NewOptions(c.viper)
 
 	releases := map[string][]*release.Release{}
+	skipped := map[string][]*config.Chart{}
+	for name, playbook := range playbookSet {
+		skipped[name] = playbook.SkippedCharts()
+	}
+
 	for name, playbook := range playbookSet {
 		entry := inv.Entries()[name]
 		entryReleases := []*release.Release{}
 
-		for _, play := range playbook.Config.Plays {
+		requiredPlays := playbook.Config.Required()
+		plays, err := playbook.Config.OrderedPlays()
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+				"error": err.Error(),
+			}).Error("Failed to order plays by their dependencies.")
+			return err
+		}
+
+		for _, play := range plays {
 			helm, err := helmutil.NewWithGetter(helmOptions, c.HelmEnv, entry.Kubeconfig(), c.Log)
 			if err != nil {
 				return fmt.Errorf("failed to create helm client instance: %s", err)
@@ -85,7 +101,7 @@ func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
 					}).Error("Failed to add helm repo for play.")
 
 					releases[name] = entryReleases
-					outErr := c.output(deployHelmStatusQueue(releases))
+					outErr := c.output(deployHelmStatusQueue(releases, skipped))
 					if outErr != nil {
 						return fmt.Errorf("%s + %s", err, outErr)
 					}
@@ -98,7 +114,7 @@ func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
 				"entry": name,
 			}).Info("Deploying play charts.")
 
-			playReleases, err := helm.DeployPlay(play)
+			playReleases, err := helm.DeployPlay(play, requiredPlays[play.Name])
 			entryReleases = append(entryReleases, playReleases...)
 			if err != nil {
 				c.Log.WithFields(logrus.Fields{
@@ -108,7 +124,7 @@ func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
 				}).Error("Failed to deploy application with helm.")
 
 				releases[name] = entryReleases
-				outErr := c.output(deployHelmStatusQueue(releases))
+				outErr := c.output(deployHelmStatusQueue(releases, skipped))
 				if outErr != nil {
 					return fmt.Errorf("%s + %s", err, outErr)
 				}
@@ -119,15 +135,16 @@ func runDeployHelm(c *Cli, cmd *cobra.Command, args []string) error {
 		releases[name] = entryReleases
 	}
 
-	return c.output(deployHelmStatusQueue(releases))
+	return c.output(deployHelmStatusQueue(releases, skipped))
 }
 
-func deployHelmStatusQueue(releases map[string][]*release.Release) printer.Queue {
+func deployHelmStatusQueue(releases map[string][]*release.Release, skipped map[string][]*config.Chart) printer.Queue {
 	printerQueue := printer.Queue{}
 	for name, entryReleases := range releases {
 		// see https://golang.org/doc/faq#closures_and_goroutines
 		name := name
 		entryReleases := entryReleases
+		entrySkipped := skipped[name]
 
 		job := printer.NewJob(func(fields []string) map[string]interface{} {
 			result := map[string]interface{}{
@@ -172,6 +189,27 @@ func deployHelmStatusQueue(releases map[string][]*release.Release) printer.Queue
 				}
 				releases = append(releases, status)
 			}
+			// charts skipped because of their when condition
+			for _, chart := range entrySkipped {
+				defaultStatus := map[string]interface{}{
+					"release":   chart.Name,
+					"namespace": chart.Namespace,
+					"status":    "skipped",
+				}
+
+				if len(fields) < 1 {
+					releases = append(releases, defaultStatus)
+					continue
+				}
+
+				status := map[string]interface{}{}
+				for _, field := range fields {
+					if val, ok := defaultStatus[field]; ok {
+						status[field] = val
+					}
+				}
+				releases = append(releases, status)
+			}
 			result["releases"] = releases
 			return result
 		})
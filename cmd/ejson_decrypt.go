@@ -0,0 +1,57 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEjsonDecryptCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "decrypt FILE",
+		Short:                 "Print the decrypted content of the given ejson file",
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runEjsonDecrypt),
+	}
+	addEjsonKeyFlags(cmd)
+
+	return cmd
+}
+
+func runEjsonDecrypt(c *Cli, cmd *cobra.Command, args []string) error {
+	file := args[0]
+
+	data, err := ejson.DecryptFile(file, getEjsonSettings(c))
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"file":  file,
+			"error": err.Error(),
+		}).Error("Failed to decrypt ejson file")
+		return err
+	}
+
+	if !c.viper.GetBool("quiet") {
+		fmt.Print(string(data))
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEjsonEditCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "edit FILE",
+		Short: "Edit the given ejson file with $EDITOR",
+		Long: `Decrypt the given ejson file, open the decrypted content in $EDITOR
+	and encrypt the result again. The decrypted content is only stored in
+	a temporary file in a private directory while the editor is running.
+	If the edited content cannot be encrypted, the error is shown and the
+	editor can be opened again, otherwise the changes are discarded.`,
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runEjsonEdit),
+	}
+	addEjsonKeyFlags(cmd)
+
+	return cmd
+}
+
+func runEjsonEdit(c *Cli, cmd *cobra.Command, args []string) error {
+	file := args[0]
+
+	stat, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+
+	decrypted, err := ejson.DecryptFile(file, getEjsonSettings(c))
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"file":  file,
+			"error": err.Error(),
+		}).Error("Failed to decrypt ejson file")
+		return err
+	}
+
+	err = editInEditor(decrypted, "kusible-*.ejson", func(edited []byte) error {
+		if bytes.Equal(decrypted, edited) {
+			c.Log.WithFields(logrus.Fields{
+				"file": file,
+			}).Info("No changes.")
+			return nil
+		}
+
+		encrypted, err := ejson.Encrypt(edited)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt: %s", err)
+		}
+		return ioutil.WriteFile(file, encrypted, stat.Mode())
+	})
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"file":  file,
+			"error": err.Error(),
+		}).Error("Failed to edit ejson file")
+		return err
+	}
+	return nil
+}
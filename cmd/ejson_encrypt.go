@@ -0,0 +1,76 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEjsonEncryptCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "encrypt FILE ...",
+		Short:                 "Encrypt all unencrypted values of the given ejson files in place",
+		Args:                  cobra.MinimumNArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runEjsonEncrypt),
+	}
+	addOutputFlags(cmd)
+
+	return cmd
+}
+
+func runEjsonEncrypt(c *Cli, cmd *cobra.Command, args []string) error {
+	printerQueue := printer.Queue{}
+	for _, file := range args {
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		file := file
+
+		err := ejson.EncryptFile(file)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"file":  file,
+				"error": err.Error(),
+			}).Error("Failed to encrypt ejson file")
+			return err
+		}
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"file":      file,
+				"encrypted": true,
+			}
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
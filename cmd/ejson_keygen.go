@@ -0,0 +1,85 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEjsonKeygenCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate a new ejson keypair",
+		Long: `Generate a new ejson keypair. With --write the private key is stored
+	in the first --ejson-key-dir instead of being printed.`,
+		Args:                  cobra.NoArgs,
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runEjsonKeygen),
+	}
+	addEjsonKeyFlags(cmd)
+	addOutputFlags(cmd)
+	cmd.Flags().BoolP("write", "w", false, "Store the private key in the key directory")
+
+	return cmd
+}
+
+func runEjsonKeygen(c *Cli, cmd *cobra.Command, args []string) error {
+	keyDir := ""
+	if c.viper.GetBool("write") {
+		keyDirs := ejson.KeyDirs(getEjsonSettings(c))
+		if len(keyDirs) > 0 {
+			keyDir = keyDirs[0]
+		}
+	}
+
+	pub, priv, err := ejson.GenerateKeypair(keyDir)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to generate ejson keypair")
+		return err
+	}
+
+	job := printer.NewJob(func(fields []string) map[string]interface{} {
+		defaultResult := map[string]interface{}{
+			"publicKey": pub,
+		}
+		if keyDir != "" {
+			defaultResult["keyDir"] = keyDir
+		} else {
+			defaultResult["privateKey"] = priv
+		}
+
+		if len(fields) < 1 {
+			return defaultResult
+		}
+
+		result := map[string]interface{}{}
+		for _, field := range fields {
+			if val, ok := defaultResult[field]; ok {
+				result[field] = val
+			}
+		}
+		return result
+	})
+
+	return c.output(printer.Queue{job})
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newEjsonRotateCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "rotate PATH ...",
+		Short: "Re-encrypt ejson files with a new public key",
+		Long: `Re-encrypt the given ejson files and all *.ejson files in the
+	given directories (including subdirectories) with the public key given
+	with --public-key. The private keys of the current public keys must be
+	available.`,
+		Args:                  cobra.MinimumNArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runEjsonRotate),
+	}
+	addEjsonKeyFlags(cmd)
+	addOutputFlags(cmd)
+	addDryRunFlags(cmd)
+	cmd.Flags().String("public-key", "", "Public key the files are encrypted with")
+
+	return cmd
+}
+
+func runEjsonRotate(c *Cli, cmd *cobra.Command, args []string) error {
+	publicKey := c.viper.GetString("public-key")
+	dryRun := c.viper.GetBool("dry-run")
+	settings := getEjsonSettings(c)
+
+	if publicKey == "" {
+		return fmt.Errorf("--public-key is required")
+	}
+
+	files, err := ejsonFiles(args)
+	if err != nil {
+		return err
+	}
+
+	printerQueue := printer.Queue{}
+	for _, file := range files {
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		file := file
+
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		currentKey, err := ejson.PublicKey(data)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"file":  file,
+				"error": err.Error(),
+			}).Error("Failed to read public key of ejson file")
+			return err
+		}
+
+		rotate := currentKey != publicKey
+		if rotate && !dryRun {
+			c.Log.WithFields(logrus.Fields{
+				"file": file,
+				"from": currentKey,
+				"to":   publicKey,
+			}).Debug("Rotating ejson file.")
+
+			err = ejson.RotateFile(file, settings, publicKey)
+			if err != nil {
+				c.Log.WithFields(logrus.Fields{
+					"file":  file,
+					"error": err.Error(),
+				}).Error("Failed to rotate ejson file")
+				return err
+			}
+		}
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"file":          file,
+				"fromPublicKey": currentKey,
+				"rotated":       rotate,
+			}
+
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
+
+// ejsonFiles returns the given files and all *.ejson files in the
+// given directories (including subdirectories)
+func ejsonFiles(paths []string) ([]string, error) {
+	result := []string{}
+	for _, path := range paths {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			result = append(result, path)
+			continue
+		}
+
+		err = filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() && filepath.Ext(file) == ".ejson" {
+				result = append(result, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
@@ -27,9 +27,20 @@ func addOutputFlags(cmd *cobra.Command) {
 
 // addEjsonFlags adds flags to control ejson decryption
 func addEjsonFlags(cmd *cobra.Command) {
+	addEjsonKeyFlags(cmd)
+	cmd.Flags().Bool("skip-decrypt", false, "Skip ejson / sops decryption")
+	cmd.Flags().Bool("strict-decrypt", false, "Fail if an ejson / sops file cannot be decrypted instead of continuing with the encrypted data")
+}
+
+// addEjsonKeyFlags adds flags to select the ejson private key
+func addEjsonKeyFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("ejson-privkey", "k", "", "EJSON private key")
-	cmd.Flags().String("ejson-key-dir", "/opt/ejson/keys", "Directory containing EJSON keys")
-	cmd.Flags().Bool("skip-decrypt", false, "Skip ejson decryption")
+	cmd.Flags().StringSlice("ejson-key-dir", []string{"/opt/ejson/keys"}, "Directories containing EJSON keys (searched in the given order)")
+}
+
+// addSopsFlags adds flags to control sops decryption
+func addSopsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("sops-age-key-file", "", "age key file used to decrypt sops files (defaults to the sops environment, e.g. SOPS_AGE_KEY_FILE)")
 }
 
 // addEvalFlags adds flags that controls spruce eval behavior
@@ -37,6 +48,23 @@ func addEvalFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("skip-eval", false, "Skip spruce operator evaluation")
 }
 
+func addHostVarsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("host-vars-dir", "", "Directory containing entry specific values, merged after all group vars")
+}
+
+func addMergeModeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("merge-mode", "override", "How group vars files are merged (override,spruce). 'spruce' supports spruce array operators like (( append )) across files")
+}
+
+func addTemplatesFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("enable-templates", false, "Render *.yml.tmpl / *.yaml.gotmpl group vars files as go templates")
+}
+
+func addExplainFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("explain", false, "Show which file set each value instead of the values")
+	cmd.Flags().String("path", "", "Only explain values below this dot separated path (e.g. vars.a.b)")
+}
+
 func addLimitFlags(cmd *cobra.Command) {
 	cmd.Flags().StringSliceP("limit", "l", []string{}, "Limit selected groups")
 }
@@ -45,11 +73,46 @@ func addSkipClusterInventoryFlags(cmd *cobra.Command) {
 	cmd.Flags().Bool("skip-cluster-inventory", false, "Skip downloading the cluster-inventory ConfigMap")
 }
 
+func addClusterInventoryFromFlags(cmd *cobra.Command) {
+	cmd.Flags().String("cluster-inventory-from", "", "Read the cluster inventories from a snapshot directory (see 'kusible snapshot') instead of the clusters")
+}
+
 func addClusterInventoryDefaultsFlags(cmd *cobra.Command) {
 	cmd.Flags().StringP("cluster-inventory-namespace", "c", "kube-system", "Default config namespace for the cluster inventory config map")
 	cmd.Flags().String("cluster-inventory-configmap", "cluster-inventory", "Name of the cluster inventory config map in the cluster inventory namespace")
 }
 
+func addFactsFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("gather-facts", false, "Gather facts about each cluster and make them available in the 'facts' key")
+	addFactsCacheFlags(cmd)
+}
+
+func addFactsCacheFlags(cmd *cobra.Command) {
+	cmd.Flags().String("facts-cache-dir", "", "Directory where gathered facts are cached (one file per inventory entry)")
+	cmd.Flags().Bool("facts-offline", false, "Do not gather facts from the clusters, read them from --facts-cache-dir instead (implies --gather-facts)")
+}
+
 func addDryRunFlags(cmd *cobra.Command) {
 	cmd.Flags().BoolP("dry-run", "C", false, "check mode (dry-run)")
 }
+
+func addWorkersFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("workers", 10, "Maximum number of inventory entries (or charts of a play) processed in parallel")
+}
+
+func addDumpOnErrorFlags(cmd *cobra.Command) {
+	cmd.Flags().String("dump-on-error", "", "Directory the merged, unevaluated playbook of each entry failing the spruce evaluation is written to")
+}
+
+func addExtraVarsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayP("extra-vars", "e", []string{}, "Values overriding the group vars: key=value (dot separated key, yaml value), @file (yaml / json) or a yaml / json map. Can be given multiple times")
+}
+
+// setFlagDefault changes the default value of a flag already added to a command
+func setFlagDefault(cmd *cobra.Command, name string, value string) {
+	flag := cmd.Flags().Lookup(name)
+	if err := flag.Value.Set(value); err != nil {
+		panic(err) // Should never happen
+	}
+	flag.DefValue = value
+}
@@ -43,20 +43,23 @@ func newGroupsCmd(c *Cli) *cobra.Command {
 }
 
 func addGroupsFlags(cmd *cobra.Command) {
-	cmd.Flags().StringP("group-vars-dir", "d", "group_vars", "Source directory to read from")
+	cmd.Flags().StringSliceP("group-vars-dir", "d", []string{"group_vars"}, "Source directories to read from. Can be given multiple times, for each group the files of later directories override the files of earlier ones")
 }
 
 func runGroups(c *Cli, cmd *cobra.Command, args []string) error {
 	filter := args[0]
 	limits := c.viper.GetStringSlice("limit")
-	groupVarsDir := c.viper.GetString("group-vars-dir")
+	groupVarsDirs, err := getGroupVarsDirs(c)
+	if err != nil {
+		return err
+	}
 
-	groups, err := groups.Groups(groupVarsDir, filter, limits)
+	groups, err := groups.GroupsOfDirectories(groupVarsDirs, filter, limits)
 	if err != nil {
 		c.Log.WithFields(logrus.Fields{
 			"filter":    filter,
 			"limits":    strings.Join(limits[:], " "),
-			"directory": groupVarsDir,
+			"directory": strings.Join(groupVarsDirs, ","),
 		}).Error("Failed to get groups")
 		return err
 	}
@@ -34,12 +34,16 @@ func newInventoryCmd(c *Cli) *cobra.Command {
 		newInventoryKubeconfigCmd(c),
 		newInventoryValuesCmd(c),
 		newInventoryLoaderCmd(c),
+		newInventoryPingCmd(c),
+		newInventoryFactsCmd(c),
+		newInventoryClusterInventoryCmd(c),
 	)
 	return cmd
 }
 
 func addInventoryFlags(cmd *cobra.Command) {
 	addEjsonFlags(cmd)
+	addSopsFlags(cmd)
 	addEvalFlags(cmd)
 	addLimitFlags(cmd)
 	addClusterInventoryDefaultsFlags(cmd)
@@ -0,0 +1,503 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bedag/kusible/pkg/inventory"
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/values"
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterInventoryChange holds the current and the desired cluster inventory
+// of a single inventory entry
+type clusterInventoryChange struct {
+	name            string
+	entry           *inventory.Entry
+	resourceVersion string
+	current         map[string]interface{}
+	desired         map[string]interface{}
+}
+
+func newInventoryClusterInventoryCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "cluster-inventory",
+		Short:                 "Read and modify the cluster-inventory ConfigMaps of inventory entries",
+		Args:                  cobra.NoArgs,
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+	}
+
+	cmd.AddCommand(
+		newInventoryClusterInventoryGetCmd(c),
+		newInventoryClusterInventorySetCmd(c),
+		newInventoryClusterInventoryDiffCmd(c),
+		newInventoryClusterInventoryEditCmd(c),
+	)
+	return cmd
+}
+
+func newInventoryClusterInventoryGetCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "get [regex]",
+		Short:                 "Get the cluster-inventory of the entries matched by the regex",
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryClusterInventoryGet),
+	}
+	addInventoryFlags(cmd)
+
+	return cmd
+}
+
+func newInventoryClusterInventorySetCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "set [regex]",
+		Short: "Modify the cluster-inventory of the entries matched by the regex",
+		Long: `Modify the cluster-inventory of the entries matched by the regex.
+	The cluster-inventory is either modified by a YAML/JSON merge patch
+	(--patch) or replaced by the content of <entry>.yaml from a
+	local directory (--from-dir). The changes are shown as diff and written
+	back to the cluster. Writes fail if the ConfigMap was modified in the meantime.`,
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryClusterInventorySet),
+	}
+	addInventoryFlags(cmd)
+	addClusterInventoryChangeFlags(cmd)
+	addDryRunFlags(cmd)
+
+	return cmd
+}
+
+func newInventoryClusterInventoryDiffCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "diff [regex]",
+		Short:                 "Show the changes 'set' would apply to the cluster-inventory of the entries matched by the regex",
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryClusterInventoryDiff),
+	}
+	addInventoryFlags(cmd)
+	addClusterInventoryChangeFlags(cmd)
+
+	return cmd
+}
+
+func newInventoryClusterInventoryEditCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "edit [regex]",
+		Short:                 "Edit the cluster-inventory of the entries matched by the regex with $EDITOR",
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryClusterInventoryEdit),
+	}
+	addInventoryFlags(cmd)
+	addDryRunFlags(cmd)
+
+	return cmd
+}
+
+func addClusterInventoryChangeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("patch", "", "YAML/JSON merge patch applied to the cluster-inventory (use @file to read it from a file)")
+	cmd.Flags().String("from-dir", "", "Directory containing the desired cluster-inventory of each entry (<entry>.yaml)")
+}
+
+func runInventoryClusterInventoryGet(c *Cli, cmd *cobra.Command, args []string) error {
+	changes, err := loadClusterInventories(c, args[0])
+	if err != nil {
+		return err
+	}
+
+	printerQueue := printer.Queue{}
+	for _, change := range changes {
+		change := change
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			if len(fields) < 1 {
+				return map[string]interface{}{
+					"entry":           change.name,
+					"resourceVersion": change.resourceVersion,
+					"inventory":       change.current,
+				}
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := change.current[field]; ok {
+					result[field] = val
+				}
+			}
+			return map[string]interface{}{
+				"entry":     change.name,
+				"inventory": result,
+			}
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
+
+func runInventoryClusterInventorySet(c *Cli, cmd *cobra.Command, args []string) error {
+	changes, err := loadClusterInventoryChanges(c, args[0])
+	if err != nil {
+		return err
+	}
+	return applyClusterInventoryChanges(c, changes, c.viper.GetBool("dry-run"))
+}
+
+func runInventoryClusterInventoryDiff(c *Cli, cmd *cobra.Command, args []string) error {
+	changes, err := loadClusterInventoryChanges(c, args[0])
+	if err != nil {
+		return err
+	}
+	return applyClusterInventoryChanges(c, changes, true)
+}
+
+func runInventoryClusterInventoryEdit(c *Cli, cmd *cobra.Command, args []string) error {
+	changes, err := loadClusterInventories(c, args[0])
+	if err != nil {
+		return err
+	}
+
+	for i, change := range changes {
+		current, err := yaml.Marshal(change.current)
+		if err != nil {
+			return err
+		}
+
+		err = editInEditor(current, fmt.Sprintf("cluster-inventory-%s-*.yaml", change.name), func(edited []byte) error {
+			var desired map[string]interface{}
+			err := yaml.Unmarshal(edited, &desired)
+			if err != nil {
+				return fmt.Errorf("failed to parse edited cluster-inventory: %s", err)
+			}
+			if desired == nil {
+				desired = map[string]interface{}{}
+			}
+			changes[i].desired = desired
+			return nil
+		})
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": change.name,
+				"error": err.Error(),
+			}).Error("Failed to edit cluster-inventory")
+			return err
+		}
+	}
+
+	return applyClusterInventoryChanges(c, changes, c.viper.GetBool("dry-run"))
+}
+
+// loadClusterInventories retrieves the current cluster-inventory of all
+// entries matched by the filter
+func loadClusterInventories(c *Cli, filter string) ([]clusterInventoryChange, error) {
+	limits := c.viper.GetStringSlice("limit")
+
+	inv, err := getInventoryWithKubeconfig(c)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := inv.EntryNames(filter, limits)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to get list of entries")
+		return nil, err
+	}
+	sort.Strings(names)
+
+	result := []clusterInventoryChange{}
+	for _, name := range names {
+		entry := inv.Entries()[name]
+
+		c.Log.WithFields(logrus.Fields{
+			"entry": name,
+		}).Debug("Retrieving cluster-inventory.")
+
+		data, resourceVersion, err := entry.ClusterInventoryData()
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+				"error": err.Error(),
+			}).Error("Failed to retrieve cluster-inventory")
+			return nil, err
+		}
+
+		result = append(result, clusterInventoryChange{
+			name:            name,
+			entry:           entry,
+			resourceVersion: resourceVersion,
+			current:         data,
+			desired:         data,
+		})
+	}
+	return result, nil
+}
+
+// loadClusterInventoryChanges retrieves the current cluster-inventory of all
+// entries matched by the filter and computes the desired cluster-inventory
+// based on the --patch / --from-dir flags
+func loadClusterInventoryChanges(c *Cli, filter string) ([]clusterInventoryChange, error) {
+	patch := c.viper.GetString("patch")
+	fromDir := c.viper.GetString("from-dir")
+
+	if (patch == "") == (fromDir == "") {
+		return nil, fmt.Errorf("exactly one of --patch or --from-dir is required")
+	}
+
+	var rawPatch []byte
+	if patch != "" {
+		var err error
+		rawPatch, err = readClusterInventoryPatch(patch)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"error": err.Error(),
+			}).Error("Failed to read cluster-inventory patch")
+			return nil, err
+		}
+	}
+
+	changes, err := loadClusterInventories(c, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []clusterInventoryChange{}
+	for _, change := range changes {
+		var desired map[string]interface{}
+		if rawPatch != nil {
+			desired, err = mergePatchClusterInventory(change.current, rawPatch)
+		} else {
+			desired, err = readClusterInventoryFromDir(fromDir, change.name)
+		}
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": change.name,
+				"error": err.Error(),
+			}).Error("Failed to compute desired cluster-inventory")
+			return nil, err
+		}
+		if desired == nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": change.name,
+				"dir":   fromDir,
+			}).Debug("No cluster-inventory file for entry, skipping.")
+			continue
+		}
+		change.desired = desired
+		result = append(result, change)
+	}
+	return result, nil
+}
+
+// applyClusterInventoryChanges shows the diff between the current and the desired
+// cluster-inventory of each entry and writes the desired cluster-inventory back
+// to the cluster unless dryRun is set
+func applyClusterInventoryChanges(c *Cli, changes []clusterInventoryChange, dryRun bool) error {
+	printerQueue := printer.Queue{}
+	modified := []clusterInventoryChange{}
+	for _, change := range changes {
+		diff, err := diffClusterInventory(change.name, change.current, change.desired)
+		if err != nil {
+			return err
+		}
+
+		if diff == "" {
+			c.Log.WithFields(logrus.Fields{
+				"entry": change.name,
+			}).Info("Cluster-inventory unchanged.")
+			continue
+		}
+
+		printerQueue = append(printerQueue, clusterInventoryDiffJob(change, diff))
+		modified = append(modified, change)
+	}
+
+	err := c.output(printerQueue)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	for _, change := range modified {
+		err := change.entry.UpdateClusterInventoryData(change.desired, change.resourceVersion)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": change.name,
+				"error": err.Error(),
+			}).Error("Failed to write cluster-inventory")
+			return err
+		}
+
+		c.Log.WithFields(logrus.Fields{
+			"entry": change.name,
+		}).Info("Cluster-inventory updated.")
+	}
+	return nil
+}
+
+// clusterInventoryDiffJob creates a printer job for the changes of the
+// cluster-inventory of a single entry
+func clusterInventoryDiffJob(change clusterInventoryChange, diff string) printer.Printable {
+	return printer.NewJob(func(fields []string) map[string]interface{} {
+		resultChanges := []map[string]interface{}{}
+		for _, valueChange := range values.Diff(change.current, change.desired) {
+			resultChange := map[string]interface{}{
+				"path": valueChange.Path,
+				"type": valueChange.Type,
+			}
+			if valueChange.Type != values.ChangeAdded {
+				resultChange["old"] = valueChange.Old
+			}
+			if valueChange.Type != values.ChangeRemoved {
+				resultChange["new"] = valueChange.New
+			}
+			resultChanges = append(resultChanges, resultChange)
+		}
+
+		defaultResult := map[string]interface{}{
+			"entry":           change.name,
+			"resourceVersion": change.resourceVersion,
+			"changes":         resultChanges,
+			"diff":            diff,
+		}
+
+		if len(fields) < 1 {
+			return defaultResult
+		}
+
+		result := map[string]interface{}{}
+		for _, field := range fields {
+			if val, ok := defaultResult[field]; ok {
+				result[field] = val
+			}
+		}
+		return result
+	})
+}
+
+func readClusterInventoryPatch(patch string) ([]byte, error) {
+	if strings.HasPrefix(patch, "@") {
+		raw, err := ioutil.ReadFile(strings.TrimPrefix(patch, "@"))
+		if err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+	return []byte(patch), nil
+}
+
+// mergePatchClusterInventory applies a YAML/JSON merge patch (RFC 7386) to the
+// given cluster-inventory
+func mergePatchClusterInventory(data map[string]interface{}, rawPatch []byte) (map[string]interface{}, error) {
+	patch, err := yaml.YAMLToJSON(rawPatch)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse patch as yaml/json: %s", err)
+	}
+
+	original, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	original, err = yaml.YAMLToJSON(original)
+	if err != nil {
+		return nil, err
+	}
+
+	patched, err := jsonpatch.MergePatch(original, patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply patch: %s", err)
+	}
+
+	var result map[string]interface{}
+	err = yaml.Unmarshal(patched, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+// readClusterInventoryFromDir reads the cluster-inventory of the given entry from
+// <dir>/<entry>.(yaml|yml|json). If no such file exists, nil is returned.
+func readClusterInventoryFromDir(dir string, name string) (map[string]interface{}, error) {
+	for _, ext := range []string{"yaml", "yml", "json"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s.%s", name, ext))
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var data map[string]interface{}
+		err = yaml.Unmarshal(raw, &data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s as yaml/json: %s", path, err)
+		}
+		if data == nil {
+			data = map[string]interface{}{}
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+// diffClusterInventory returns a unified diff of the yaml representation of
+// the current and the desired cluster-inventory
+func diffClusterInventory(name string, current map[string]interface{}, desired map[string]interface{}) (string, error) {
+	a, err := yaml.Marshal(current)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(desired)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(a)),
+		B:        difflib.SplitLines(string(b)),
+		FromFile: fmt.Sprintf("%s (cluster)", name),
+		ToFile:   fmt.Sprintf("%s (desired)", name),
+		Context:  3,
+	})
+}
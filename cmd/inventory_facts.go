@@ -0,0 +1,143 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newInventoryFactsCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "facts [regex]",
+		Short: "Gather facts about the clusters of the entries matched by the regex",
+		Long: `Gather facts about the clusters of the entries matched by the regex.
+	If --facts-cache-dir is given, the gathered facts will be written to
+	the cache directory and can be used to render playbooks offline.`,
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryFacts),
+	}
+	addInventoryFlags(cmd)
+	addFactsCacheFlags(cmd)
+	addWorkersFlags(cmd)
+
+	return cmd
+}
+
+func runInventoryFacts(c *Cli, cmd *cobra.Command, args []string) error {
+	filter := args[0]
+	limits := c.viper.GetStringSlice("limit")
+	workers := c.viper.GetInt("workers")
+	if workers < 1 {
+		workers = 1
+	}
+
+	inv, err := getInventoryWithKubeconfig(c)
+	if err != nil {
+		return err
+	}
+
+	names, err := inv.EntryNames(filter, limits)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to get list of entries")
+		return err
+	}
+	sort.Strings(names)
+
+	results := make(map[string]*map[string]interface{}, len(names))
+	errs := make(map[string]error)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workers)
+
+	for _, name := range names {
+		entry := inv.Entries()[name]
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		name := name
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+			}).Debug("Gathering facts.")
+
+			facts, err := entry.Facts()
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				c.Log.WithFields(logrus.Fields{
+					"entry": name,
+					"error": err.Error(),
+				}).Error("Failed to gather facts")
+				errs[name] = err
+				return
+			}
+			results[name] = facts
+		}()
+	}
+	wg.Wait()
+
+	// report the error of the first failed entry
+	for _, name := range names {
+		if err, ok := errs[name]; ok {
+			return err
+		}
+	}
+
+	printerQueue := printer.Queue{}
+	for _, name := range names {
+		name := name
+		facts := results[name]
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			if len(fields) < 1 {
+				return map[string]interface{}{
+					"entry": name,
+					"facts": *facts,
+				}
+			}
+
+			resultFacts := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := (*facts)[field]; ok {
+					resultFacts[field] = val
+				}
+			}
+
+			return map[string]interface{}{
+				"entry": name,
+				"facts": resultFacts,
+			}
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
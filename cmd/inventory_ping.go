@@ -0,0 +1,135 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bedag/kusible/pkg/inventory"
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newInventoryPingCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:                   "ping [regex]",
+		Short:                 "Check if the clusters of the entries matched by the regex are reachable",
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runInventoryPing),
+	}
+	addInventoryFlags(cmd)
+	addWorkersFlags(cmd)
+	cmd.Flags().Duration("timeout", 10*time.Second, "Timeout of the requests to each cluster (0 disables the timeout)")
+
+	return cmd
+}
+
+func runInventoryPing(c *Cli, cmd *cobra.Command, args []string) error {
+	filter := args[0]
+	limits := c.viper.GetStringSlice("limit")
+	workers := c.viper.GetInt("workers")
+	timeout := c.viper.GetDuration("timeout")
+	if workers < 1 {
+		workers = 1
+	}
+
+	inv, err := getInventoryWithKubeconfig(c)
+	if err != nil {
+		return err
+	}
+
+	names, err := inv.EntryNames(filter, limits)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to get list of entries")
+		return err
+	}
+	sort.Strings(names)
+
+	results := make(map[string]*inventory.PingResult, len(names))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workers)
+
+	for _, name := range names {
+		entry := inv.Entries()[name]
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		name := name
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+			}).Debug("Pinging entry.")
+
+			result := entry.Ping(timeout)
+
+			mutex.Lock()
+			results[name] = result
+			mutex.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	printerQueue := printer.Queue{}
+	for _, name := range names {
+		name := name
+		result := results[name]
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"entry":     name,
+				"reachable": result.Reachable,
+				"version":   result.ServerVersion,
+				"latency":   result.Latency.Round(time.Millisecond).String(),
+				"authError": result.AuthError,
+				"error":     result.Error,
+			}
+			if result.CertificateExpiry != nil {
+				defaultResult["certExpiry"] = result.CertificateExpiry.Format(time.RFC3339)
+			}
+			if result.CertificateError != "" {
+				defaultResult["certError"] = result.CertificateError
+			}
+
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
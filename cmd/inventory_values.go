@@ -17,6 +17,8 @@ limitations under the License.
 package cmd
 
 import (
+	"strings"
+
 	"github.com/bedag/kusible/internal/third_party/deepcopy"
 	"github.com/bedag/kusible/internal/wrapper/spruce"
 	"github.com/bedag/kusible/pkg/printer"
@@ -35,7 +37,15 @@ func newInventoryValuesCmd(c *Cli) *cobra.Command {
 	}
 	addInventoryFlags(cmd)
 	addGroupsFlags(cmd)
+	addMergeModeFlags(cmd)
+	addTemplatesFlags(cmd)
+	addHostVarsFlags(cmd)
 	addSkipClusterInventoryFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
+	addFactsFlags(cmd)
+	addExplainFlags(cmd)
+	addWorkersFlags(cmd)
+	addExtraVarsFlags(cmd)
 
 	return cmd
 }
@@ -44,6 +54,9 @@ func runInventoryValues(c *Cli, cmd *cobra.Command, args []string) error {
 	filter := args[0]
 	skipClusterInv := c.viper.GetBool("skip-cluster-inventory")
 	skipEval := c.viper.GetBool("skip-eval")
+	gatherFacts := getGatherFacts(c)
+	explain := c.viper.GetBool("explain")
+	path := c.viper.GetString("path")
 
 	targets, err := loadTargets(c, filter)
 	if err != nil {
@@ -54,14 +67,58 @@ func runInventoryValues(c *Cli, cmd *cobra.Command, args []string) error {
 	for name, target := range targets.Targets() {
 		values := target.Values().Map()
 		clusterInventory := map[string]interface{}{}
+		var ci *map[string]interface{}
 
 		if !skipClusterInv {
-			ci, err := target.Entry().ClusterInventory()
+			ci, err = target.Entry().ClusterInventory()
 			if err != nil {
 				return err
 			}
 			clusterInventory = *ci
 		}
+		ctx := target.Context(ci)
+
+		if gatherFacts {
+			facts, err := target.Entry().Facts()
+			if err != nil {
+				return err
+			}
+			clusterInventory["facts"] = *facts
+		}
+
+		if explain {
+			mergeResult, err := deepcopy.Map(clusterInventory)
+			if err != nil {
+				return err
+			}
+			err = mergo.Merge(&mergeResult, values, mergo.WithOverride)
+			if err != nil {
+				return err
+			}
+			err = spruce.EvalWithContext(&mergeResult, skipEval, []string{}, ctx)
+			if err != nil {
+				return err
+			}
+
+			provenances := target.Values().Explain(mergeResult, path)
+			// values not set by any file were either retrieved from the
+			// cluster or computed by a spruce operator
+			for i, provenance := range provenances {
+				if provenance.File != "" {
+					continue
+				}
+				switch {
+				case !hasPath(clusterInventory, provenance.Path):
+					provenances[i].File = "<computed>"
+				case strings.HasPrefix(provenance.Path, "facts."):
+					provenances[i].File = "facts"
+				default:
+					provenances[i].File = "cluster-inventory"
+				}
+			}
+			printerQueue = append(printerQueue, explainQueue(name, provenances)...)
+			continue
+		}
 
 		// see https://golang.org/doc/faq#closures_and_goroutines
 		name := name
@@ -70,7 +127,7 @@ func runInventoryValues(c *Cli, cmd *cobra.Command, args []string) error {
 			// TODO error handling
 			mergeResult, _ := deepcopy.Map(clusterInventory)
 			mergo.Merge(&mergeResult, values, mergo.WithOverride)
-			spruce.Eval(&mergeResult, skipEval, []string{})
+			spruce.EvalWithContext(&mergeResult, skipEval, []string{}, ctx)
 
 			defaultResult := map[string]interface{}{
 				"entry":  name,
@@ -97,3 +154,21 @@ func runInventoryValues(c *Cli, cmd *cobra.Command, args []string) error {
 	}
 	return c.output(printerQueue)
 }
+
+// hasPath returns true if data contains the given dot separated path
+// (or one of its parents as non-map value)
+func hasPath(data map[string]interface{}, path string) bool {
+	current := data
+	for _, key := range strings.Split(path, ".") {
+		value, ok := current[key]
+		if !ok {
+			return false
+		}
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return true
+		}
+		current = m
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/values"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newLintValuesCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "values [filter]",
+		Short: "Check the values of all inventory entries matched by filter",
+		Long: `Compile the values of all inventory entries matched by filter
+	(default: all entries) and report
+	  * (( grab )) operators referencing undefined paths
+	  * values that are overridden by more specific groups for all entries
+	  * group files / directories no inventory entry is a member of
+	    (only if all entries are linted, i.e. without filter and --limit)
+	  * ejson files that cannot be decrypted
+	The command fails if any issue was found.`,
+		Args:                  cobra.MaximumNArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runLintValues),
+	}
+	addInventoryFlags(cmd)
+	addGroupsFlags(cmd)
+	addMergeModeFlags(cmd)
+	addTemplatesFlags(cmd)
+	addHostVarsFlags(cmd)
+	addExtraVarsFlags(cmd)
+	addSkipClusterInventoryFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
+	addFactsFlags(cmd)
+	addWorkersFlags(cmd)
+
+	return cmd
+}
+
+func runLintValues(c *Cli, cmd *cobra.Command, args []string) error {
+	filter := ".*"
+	if len(args) > 0 {
+		filter = args[0]
+	}
+	skipClusterInv := c.viper.GetBool("skip-cluster-inventory")
+	gatherFacts := getGatherFacts(c)
+	groupVarsDirs, err := getGroupVarsDirs(c)
+	if err != nil {
+		return err
+	}
+
+	targets, err := loadTargets(c, filter)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(targets.Targets()))
+	for name := range targets.Targets() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	linter := values.NewLinter(groupVarsDirs[0], values.Options{Ejson: getEjsonSettings(c), Overlays: groupVarsDirs[1:]})
+	// unused groups can only be determined if all entries are linted
+	if filter != ".*" || len(c.viper.GetStringSlice("limit")) > 0 {
+		c.Log.Info("Only a subset of the inventory entries is linted, skipping the check for unused groups.")
+		linter.SkipUnusedGroups()
+	}
+	for _, name := range names {
+		target := targets.Targets()[name]
+		data, _, err := entryValues(target, skipClusterInv, gatherFacts)
+		if err != nil {
+			return err
+		}
+
+		linter.Add(name, target.Groups(), target.Values(), data)
+	}
+
+	issues, err := linter.Issues()
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to lint values.")
+		return err
+	}
+
+	printerQueue := printer.Queue{}
+	for _, issue := range issues {
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		issue := issue
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"type":    issue.Type,
+				"entry":   issue.Entry,
+				"file":    issue.File,
+				"path":    issue.Path,
+				"message": issue.Message,
+			}
+
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	err = c.output(printerQueue)
+	if err != nil {
+		return err
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d values lint issues", len(issues))
+	}
+	return nil
+}
@@ -38,8 +38,20 @@ func newRenderCmd(c *Cli) *cobra.Command {
 	return cmd
 }
 
+// addRenderFlags adds the flags shared by the render, deploy and uninstall
+// commands. Flags that only make sense without cluster changes (like
+// --cluster-inventory-from) are added by the render commands themselves.
 func addRenderFlags(cmd *cobra.Command) {
 	addGroupsFlags(cmd)
+	addMergeModeFlags(cmd)
+	addTemplatesFlags(cmd)
+	addHostVarsFlags(cmd)
 	addInventoryFlags(cmd)
 	addSkipClusterInventoryFlags(cmd)
+	addFactsFlags(cmd)
+	addWorkersFlags(cmd)
+	addDumpOnErrorFlags(cmd)
+	addExtraVarsFlags(cmd)
+	// never render / deploy encrypted data unless explicitly requested
+	setFlagDefault(cmd, "strict-decrypt", "true")
 }
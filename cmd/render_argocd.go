@@ -26,14 +26,15 @@ import (
 
 func newRenderArgoCDCmd(c *Cli) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:                   "argocd [playbook]",
+		Use:                   "argocd [playbook...]",
 		Short:                 "Use render the given playbook into a set of ArgoCD Application resources",
-		Args:                  cobra.ExactArgs(1),
+		Args:                  cobra.MinimumNArgs(1),
 		TraverseChildren:      true,
 		DisableFlagsInUseLine: true,
 		RunE:                  c.wrap(runRenderArgoCD),
 	}
 	addRenderFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
 	cmd.Flags().String("argocd-namespace", "argocd", "Namespace where ArgoCD is looking for ArgoCD applications")
 	cmd.Flags().String("argocd-project", "default", "The ArgoCD project to which the applications should be assigned")
 
@@ -41,11 +42,11 @@ func newRenderArgoCDCmd(c *Cli) *cobra.Command {
 }
 
 func runRenderArgoCD(c *Cli, cmd *cobra.Command, args []string) error {
-	playbookFile := args[0]
+	playbookFiles := args
 	namespace := c.viper.GetString("argocd-namespace")
 	project := c.viper.GetString("argocd-project")
 
-	playbookSet, err := loadPlaybooks(c, playbookFile)
+	playbookSet, err := loadPlaybooks(c, playbookFiles)
 	if err != nil {
 		return err
 	}
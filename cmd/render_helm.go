@@ -19,6 +19,7 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/bedag/kusible/pkg/inventory"
 	"github.com/bedag/kusible/pkg/printer"
 	helmutil "github.com/bedag/kusible/pkg/wrapper/helm"
 	"github.com/sirupsen/logrus"
@@ -28,35 +29,54 @@ import (
 
 func newRenderHelmCmd(c *Cli) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:                   "helm [playbook]",
+		Use:                   "helm [playbook...]",
 		Short:                 "Use helm to render manifests for an inventory entry",
-		Args:                  cobra.ExactArgs(1),
+		Args:                  cobra.MinimumNArgs(1),
 		TraverseChildren:      true,
 		DisableFlagsInUseLine: true,
 		RunE:                  c.wrap(runRenderHelm),
 	}
 	addRenderFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
 	helmutil.AddHelmTemplateFlags(cmd)
 
 	return cmd
 }
 
 func runRenderHelm(c *Cli, cmd *cobra.Command, args []string) error {
-	playbookFile := args[0]
+	playbookFiles := args
 
-	playbookSet, err := loadPlaybooks(c, playbookFile)
+	playbookSet, err := loadPlaybooks(c, playbookFiles)
 	if err != nil {
 		return err
 	}
 
 	helmOptions := helmutil.NewOptions(c.viper)
-	helm, err := helmutil.New(helmOptions, c.HelmEnv, c.Log)
-	if err != nil {
-		return fmt.Errorf("failed to create helm client instance: %s", err)
-	}
+	snapshotDir := c.viper.GetString("cluster-inventory-from")
 
 	bigManifest := ""
 	for name, playbook := range playbookSet {
+		entryHelmOptions := helmOptions
+		if snapshotDir != "" {
+			// use the api versions recorded in the snapshot (if any) for
+			// Capabilities.APIVersions
+			snapshot, err := inventory.ReadSnapshot(snapshotDir, name)
+			if err != nil {
+				c.Log.WithFields(logrus.Fields{
+					"entry": name,
+					"error": err.Error(),
+				}).Error("Failed to read cluster snapshot.")
+
+				return err
+			}
+			entryHelmOptions.ExtraAPIs = append(append([]string{}, helmOptions.ExtraAPIs...), snapshot.APIVersions...)
+		}
+
+		helm, err := helmutil.New(entryHelmOptions, c.HelmEnv, c.Log)
+		if err != nil {
+			return fmt.Errorf("failed to create helm client instance: %s", err)
+		}
+
 		for _, play := range playbook.Config.Plays {
 			for _, repo := range play.Repos {
 				c.Log.WithFields(logrus.Fields{
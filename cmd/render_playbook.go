@@ -24,23 +24,24 @@ import (
 
 func newRenderPlaybookCmd(c *Cli) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:                   "playbook [playbook]",
+		Use:                   "playbook [playbook...]",
 		Short:                 "Render the given playbook",
-		Args:                  cobra.ExactArgs(1),
+		Args:                  cobra.MinimumNArgs(1),
 		TraverseChildren:      true,
 		DisableFlagsInUseLine: true,
 		RunE:                  c.wrap(runRenderPlaybook),
 	}
 	addRenderFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
 
 	return cmd
 }
 
 func runRenderPlaybook(c *Cli, cmd *cobra.Command, args []string) error {
-	playbookFile := args[0]
+	playbookFiles := args
 	skipEval := c.viper.GetBool("skip-eval")
 
-	playbookSet, err := loadPlaybooks(c, playbookFile)
+	playbookSet, err := loadPlaybooks(c, playbookFiles)
 	if err != nil {
 		return err
 	}
@@ -34,6 +34,7 @@ func NewRootCommand(c *Cli) *cobra.Command {
 	rootCmd.PersistentFlags().Bool("json-log", false, "log as json")
 	rootCmd.PersistentFlags().Bool("log-functions", false, "log function names (performance impact!)")
 	rootCmd.PersistentFlags().BoolP("quiet", "q", false, "Suppress all normal output")
+	rootCmd.PersistentFlags().String("source-cache-dir", "", "Directory remote inventories, group vars and playbooks are cached in (defaults to kusible/sources in the user cache directory)")
 
 	c.bindAllFlags(rootCmd)
 
@@ -45,6 +46,9 @@ func NewRootCommand(c *Cli) *cobra.Command {
 		newInventoryCmd(c),
 		newDeployCmd(c),
 		newUninstallCmd(c),
+		newSnapshotCmd(c),
+		newEjsonCmd(c),
+		newLintCmd(c),
 	)
 
 	return rootCmd
@@ -0,0 +1,119 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"sort"
+
+	"github.com/bedag/kusible/pkg/inventory"
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newSnapshotCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "snapshot [regex]",
+		Short: "Record the cluster inventories of the entries matched by the regex",
+		Long: `Record the cluster inventories (and optionally the api versions) of
+	the entries matched by the regex in a local directory (one file per entry).
+	Use --cluster-inventory-from with the render commands to render
+	playbooks based on the snapshot without cluster access.`,
+		Args:                  cobra.ExactArgs(1),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runSnapshot),
+	}
+	addInventoryFlags(cmd)
+	cmd.Flags().String("dir", "snapshot", "Directory the snapshot is written to")
+	cmd.Flags().Bool("api-versions", false, "Also record the api versions of each cluster (used for Capabilities.APIVersions when rendering with helm)")
+
+	return cmd
+}
+
+func runSnapshot(c *Cli, cmd *cobra.Command, args []string) error {
+	filter := args[0]
+	limits := c.viper.GetStringSlice("limit")
+	dir := c.viper.GetString("dir")
+	apiVersions := c.viper.GetBool("api-versions")
+
+	inv, err := getInventoryWithKubeconfig(c)
+	if err != nil {
+		return err
+	}
+
+	names, err := inv.EntryNames(filter, limits)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"error": err.Error(),
+		}).Error("Failed to get list of entries")
+		return err
+	}
+	sort.Strings(names)
+
+	printerQueue := printer.Queue{}
+	for _, name := range names {
+		entry := inv.Entries()[name]
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		name := name
+
+		c.Log.WithFields(logrus.Fields{
+			"entry": name,
+		}).Debug("Recording snapshot.")
+
+		snapshot, err := entry.Snapshot(apiVersions)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+				"error": err.Error(),
+			}).Error("Failed to record snapshot")
+			return err
+		}
+
+		err = inventory.WriteSnapshot(dir, name, snapshot)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+				"error": err.Error(),
+			}).Error("Failed to write snapshot")
+			return err
+		}
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"entry":       name,
+				"dir":         dir,
+				"apiVersions": len(snapshot.APIVersions),
+			}
+
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+
+	return c.output(printerQueue)
+}
@@ -27,9 +27,9 @@ import (
 
 func newUninstallHelmCmd(c *Cli) *cobra.Command {
 	var cmd = &cobra.Command{
-		Use:                   "helm [playbook]",
+		Use:                   "helm [playbook...]",
 		Short:                 "Uninstall an application deployed with helm",
-		Args:                  cobra.ExactArgs(1),
+		Args:                  cobra.MinimumNArgs(1),
 		TraverseChildren:      true,
 		DisableFlagsInUseLine: true,
 		RunE:                  c.wrap(runUninstallHelm),
@@ -42,14 +42,18 @@ func newUninstallHelmCmd(c *Cli) *cobra.Command {
 }
 
 func runUninstallHelm(c *Cli, cmd *cobra.Command, args []string) error {
-	playbookFile := args[0]
+	playbookFiles := args
 
 	inv, err := getInventoryWithKubeconfig(c)
 	if err != nil {
 		return err
 	}
 
-	playbookSet, err := loadPlaybooks(c, playbookFile)
+	// charts are uninstalled regardless of their when condition, it might
+	// have been true when they were deployed
+	options := playbookOptions(c)
+	options.IgnoreConditions = true
+	playbookSet, err := loadPlaybooksWithOptions(c, playbookFiles, options)
 	if err != nil {
 		return err
 	}
@@ -61,7 +65,18 @@ func runUninstallHelm(c *Cli, cmd *cobra.Command, args []string) error {
 		entry := inv.Entries()[name]
 		entryStatus := []string{}
 
-		for _, play := range playbook.Config.Plays {
+		plays, err := playbook.Config.OrderedPlays()
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"entry": name,
+				"error": err.Error(),
+			}).Error("Failed to order plays by their dependencies.")
+			return err
+		}
+
+		// uninstall the plays in the reverse order of the deployment
+		for i := len(plays) - 1; i >= 0; i-- {
+			play := plays[i]
 			helm, err := helmutil.NewWithGetter(helmOptions, c.HelmEnv, entry.Kubeconfig(), c.Log)
 			if err != nil {
 				return fmt.Errorf("failed to create helm client instance: %s", err)
@@ -17,34 +17,199 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
+	"encoding/csv"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"strings"
 
+	"github.com/bedag/kusible/internal/third_party/deepcopy"
+	"github.com/bedag/kusible/internal/wrapper/spruce"
 	"github.com/bedag/kusible/pkg/inventory"
 	invconfig "github.com/bedag/kusible/pkg/inventory/config"
 	"github.com/bedag/kusible/pkg/playbook"
+	"github.com/bedag/kusible/pkg/source"
 	"github.com/bedag/kusible/pkg/target"
+	"github.com/bedag/kusible/pkg/values"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/pkg/wrapper/sops"
+	"github.com/imdario/mergo"
 	"github.com/sirupsen/logrus"
 )
 
 func getEjsonSettings(c *Cli) ejson.Settings {
 	return ejson.Settings{
 		PrivKey:     c.viper.GetString("ejson-privkey"),
-		KeyDir:      c.viper.GetString("ejson-key-dir"),
+		KeyDirs:     c.viper.GetStringSlice("ejson-key-dir"),
 		SkipDecrypt: c.viper.GetBool("skip-decrypt"),
+		Strict:      c.viper.GetBool("strict-decrypt"),
+	}
+}
+
+func getSopsSettings(c *Cli) sops.Settings {
+	return sops.Settings{
+		AgeKeyFile:  c.viper.GetString("sops-age-key-file"),
+		SkipDecrypt: c.viper.GetBool("skip-decrypt"),
+		Strict:      c.viper.GetBool("strict-decrypt"),
+	}
+}
+
+// getGatherFacts returns true if facts should be made available in the
+// "facts" key. Reading the facts from the cache (--facts-offline) implies
+// --gather-facts.
+func getGatherFacts(c *Cli) bool {
+	return c.viper.GetBool("gather-facts") || c.viper.GetBool("facts-offline")
+}
+
+// resolveSource returns a local path for the given (remote) inventory,
+// group vars or playbook source (see pkg/source)
+func resolveSource(c *Cli, raw string) (string, error) {
+	if !source.IsRemote(raw) {
+		return raw, nil
+	}
+
+	if c.sources == nil {
+		resolver, err := source.NewResolver(c.viper.GetString("source-cache-dir"))
+		if err != nil {
+			return "", err
+		}
+		c.sources = resolver
+	}
+
+	path, err := c.sources.Resolve(raw)
+	if err != nil {
+		c.Log.WithFields(logrus.Fields{
+			"source": raw,
+			"error":  err.Error(),
+		}).Error("Failed to fetch source.")
+		return "", err
+	}
+
+	c.Log.WithFields(logrus.Fields{
+		"source": raw,
+		"path":   path,
+	}).Debug("Fetched source.")
+	return path, nil
+}
+
+// resolveSources is the same as resolveSource() for a list of sources
+func resolveSources(c *Cli, raw []string) ([]string, error) {
+	result := make([]string, 0, len(raw))
+	for _, r := range raw {
+		path, err := resolveSource(c, r)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, path)
+	}
+	return result, nil
+}
+
+// getGroupVarsDirs returns the local paths of the group vars directories
+// given with --group-vars-dir (the first one is the values directory, all
+// others are overlays)
+func getGroupVarsDirs(c *Cli) ([]string, error) {
+	return resolveGroupVarsDirs(c, c.viper.GetStringSlice("group-vars-dir"))
+}
+
+func resolveGroupVarsDirs(c *Cli, groupVarsDirs []string) ([]string, error) {
+	if len(groupVarsDirs) < 1 {
+		return nil, fmt.Errorf("no group vars directory given")
+	}
+	return resolveSources(c, groupVarsDirs)
+}
+
+// getValuesOptions returns the options used to compile the values of targets.
+// Spruce evaluation is always skipped as it happens when compiling the playbooks.
+func getValuesOptions(c *Cli) (values.Options, error) {
+	extraVars, err := getExtraVars(c)
+	if err != nil {
+		return values.Options{}, err
+	}
+	return values.Options{
+		SkipEval:    true,
+		Ejson:       getEjsonSettings(c),
+		Sops:        getSopsSettings(c),
+		HostVarsDir: c.viper.GetString("host-vars-dir"),
+		MergeMode:   c.viper.GetString("merge-mode"),
+		Templates:   c.viper.GetBool("enable-templates"),
+		ExtraVars:   extraVars,
+	}, nil
+}
+
+// getExtraVars parses the values given with --extra-vars
+func getExtraVars(c *Cli) (map[string]interface{}, error) {
+	args, err := getStringArray(c, "extra-vars")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < 1 {
+		return nil, nil
+	}
+
+	c.Log.WithFields(logrus.Fields{
+		"extra-vars": strings.Join(args, " "),
+	}).Debug("Using extra vars.")
+
+	return values.ParseExtraVars(args)
+}
+
+// getStringArray returns the values of a string array flag. Viper
+// returns string array flags as csv encoded string (e.g. "[a,b]") and
+// GetStringSlice() would split json values containing spaces.
+func getStringArray(c *Cli, name string) ([]string, error) {
+	switch value := c.viper.Get(name).(type) {
+	case []string:
+		return value, nil
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, v := range value {
+			result = append(result, fmt.Sprint(v))
+		}
+		return result, nil
+	case string:
+		value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+		if value == "" {
+			return []string{}, nil
+		}
+		return csv.NewReader(strings.NewReader(value)).Read()
+	case nil:
+		return []string{}, nil
+	default:
+		return nil, fmt.Errorf("invalid value for %s: %v", name, value)
 	}
 }
 
 func loadInventory(c *Cli, skipKubeconfig bool) (*inventory.Inventory, error) {
+	return loadInventoryFromPath(c, c.viper.GetString("inventory"), skipKubeconfig)
+}
+
+// loadInventoryFromPath loads the inventory at the given path instead of
+// the one given with --inventory
+func loadInventoryFromPath(c *Cli, inventoryPath string, skipKubeconfig bool) (*inventory.Inventory, error) {
+	inventoryPath, err := resolveSource(c, inventoryPath)
+	if err != nil {
+		return nil, err
+	}
 	ejsonSettings := getEjsonSettings(c)
-	inventoryPath := c.viper.GetString("inventory")
 
 	clusterInventoryDefaults := invconfig.ClusterInventory{
 		Namespace: c.viper.GetString("cluster-inventory-namespace"),
 		ConfigMap: c.viper.GetString("cluster-inventory-configmap"),
 	}
 
+	var factsCache *inventory.FactsCache
+	factsCacheDir := c.viper.GetString("facts-cache-dir")
+	factsOffline := c.viper.GetBool("facts-offline")
+	if factsCacheDir != "" || factsOffline {
+		factsCache = &inventory.FactsCache{
+			Dir:     factsCacheDir,
+			Offline: factsOffline,
+		}
+	}
+
 	c.Log.WithFields(logrus.Fields{
 		"path":              inventoryPath,
 		"load-kubeconfig":   !skipKubeconfig,
@@ -59,6 +224,23 @@ func loadInventory(c *Cli, skipKubeconfig bool) (*inventory.Inventory, error) {
 		return nil, err
 	}
 
+	if snapshotDir := c.viper.GetString("cluster-inventory-from"); snapshotDir != "" {
+		c.Log.WithFields(logrus.Fields{
+			"dir": snapshotDir,
+		}).Trace("Reading cluster inventories from snapshot.")
+
+		inventory.SetSnapshotDir(snapshotDir)
+	}
+
+	if factsCache != nil {
+		c.Log.WithFields(logrus.Fields{
+			"dir":     factsCache.Dir,
+			"offline": factsCache.Offline,
+		}).Trace("Using facts cache.")
+
+		inventory.SetFactsCache(factsCache)
+	}
+
 	c.Log.WithFields(logrus.Fields{
 		"entries": len(inventory.Entries()),
 	}).Trace("Successfully loaded inventory.")
@@ -90,18 +272,37 @@ func loadTargets(c *Cli, filter string) (*target.Targets, error) {
 }
 
 func loadTargetsWithInventory(c *Cli, filter string, inv *inventory.Inventory) (*target.Targets, error) {
-	limits := c.viper.GetStringSlice("limit")
-	groupVarsDir := c.viper.GetString("group-vars-dir")
+	options, err := getValuesOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	return loadTargetsWithOptions(c, filter, inv, c.viper.GetStringSlice("group-vars-dir"), options)
+}
 
-	ejsonSettings := getEjsonSettings(c)
+// loadTargetsWithOptions loads the targets with the values of the given
+// group vars directories instead of the ones given with --group-vars-dir
+func loadTargetsWithOptions(c *Cli, filter string, inv *inventory.Inventory, groupVarsDirs []string, options values.Options) (*target.Targets, error) {
+	groupVarsDirs, err := resolveGroupVarsDirs(c, groupVarsDirs)
+	if err != nil {
+		return nil, err
+	}
+	options.Overlays = groupVarsDirs[1:]
+	limits := c.viper.GetStringSlice("limit")
 
 	c.Log.WithFields(logrus.Fields{
 		"limits":         strings.Join(limits, ","),
 		"filter":         filter,
-		"group-vars-dir": groupVarsDir,
+		"group-vars-dir": strings.Join(groupVarsDirs, ","),
+		"host-vars-dir":  options.HostVarsDir,
 	}).Trace("Loading targets from inventory.")
 
-	targets, err := target.NewTargets(filter, limits, groupVarsDir, inv, true, &ejsonSettings)
+	// commands without a --workers flag use one worker per cpu
+	targetOptions := target.Options{
+		Values:  options,
+		Workers: c.viper.GetInt("workers"),
+	}
+
+	targets, err := target.NewTargets(filter, limits, groupVarsDirs[0], inv, targetOptions)
 	if err != nil {
 		c.Log.WithFields(logrus.Fields{
 			"error": err.Error(),
@@ -109,6 +310,13 @@ func loadTargetsWithInventory(c *Cli, filter string, inv *inventory.Inventory) (
 		return nil, err
 	}
 
+	for _, name := range targets.Names() {
+		c.Log.WithFields(logrus.Fields{
+			"entry": name,
+			"files": strings.Join(targets.Targets()[name].Values().Files(), " "),
+		}).Debug("Ordered list of merged files.")
+	}
+
 	c.Log.WithFields(logrus.Fields{
 		"targets": len(targets.Targets()),
 	}).Trace("Successfully loaded targets from inventory.")
@@ -116,25 +324,44 @@ func loadTargetsWithInventory(c *Cli, filter string, inv *inventory.Inventory) (
 	return targets, nil
 }
 
-func loadPlaybooks(c *Cli, playbookFile string) (playbook.Set, error) {
+func loadPlaybooks(c *Cli, playbookFiles []string) (playbook.Set, error) {
+	return loadPlaybooksWithOptions(c, playbookFiles, playbookOptions(c))
+}
+
+func loadPlaybooksWithOptions(c *Cli, playbookFiles []string, options playbook.Options) (playbook.Set, error) {
 	targets, err := loadTargets(c, ".*")
 	if err != nil {
 		return nil, err
 	}
-	return loadPlaybooksWithTargets(c, playbookFile, targets)
+	return loadPlaybooksWithTargets(c, playbookFiles, targets, options)
 }
 
-func loadPlaybooksWithTargets(c *Cli, playbookFile string, targets *target.Targets) (playbook.Set, error) {
-	skipEval := c.viper.GetBool("skip-eval")
-	skipClusterInv := c.viper.GetBool("skip-cluster-inventory")
+func playbookOptions(c *Cli) playbook.Options {
+	return playbook.Options{
+		SkipEval:       c.viper.GetBool("skip-eval"),
+		SkipClusterInv: c.viper.GetBool("skip-cluster-inventory"),
+		GatherFacts:    getGatherFacts(c),
+		Version:        Version,
+		DumpDir:        c.viper.GetString("dump-on-error"),
+	}
+}
+
+func loadPlaybooksWithTargets(c *Cli, playbookFiles []string, targets *target.Targets, options playbook.Options) (playbook.Set, error) {
+	playbookFiles, err := resolveSources(c, playbookFiles)
+	if err != nil {
+		return nil, err
+	}
 
 	c.Log.WithFields(logrus.Fields{
-		"playbook-file":          playbookFile,
-		"spruce-eval":            !skipEval,
-		"load-cluster-inventory": !skipClusterInv,
+		"playbook-files":         playbookFiles,
+		"spruce-eval":            !options.SkipEval,
+		"load-cluster-inventory": !options.SkipClusterInv,
+		"gather-facts":           options.GatherFacts,
+		"dump-on-error":          options.DumpDir,
+		"ignore-conditions":      options.IgnoreConditions,
 	}).Trace("Loading playbooks for targets.")
 
-	playbooks, err := playbook.NewSet(playbookFile, targets, skipEval, skipClusterInv)
+	playbooks, err := playbook.NewSet(playbookFiles, targets, options)
 	if err != nil {
 		c.Log.WithFields(logrus.Fields{
 			"error": err.Error(),
@@ -160,3 +387,109 @@ func loadPlaybooksWithTargets(c *Cli, playbookFile string, targets *target.Targe
 
 	return playbooks, nil
 }
+
+// entryValues merges the values of the given target over its cluster
+// inventory (and facts). The result is not evaluated by spruce, the
+// returned context is used for the evaluation.
+func entryValues(t *target.Target, skipClusterInv bool, gatherFacts bool) (map[string]interface{}, *spruce.Context, error) {
+	clusterInventory := map[string]interface{}{}
+	var ci *map[string]interface{}
+
+	if !skipClusterInv {
+		var err error
+		ci, err = t.Entry().ClusterInventory()
+		if err != nil {
+			return nil, nil, err
+		}
+		clusterInventory = *ci
+	}
+
+	result, err := deepcopy.Map(clusterInventory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if gatherFacts {
+		facts, err := t.Entry().Facts()
+		if err != nil {
+			return nil, nil, err
+		}
+		result["facts"] = *facts
+	}
+
+	err = mergo.Merge(&result, t.Values().Map(), mergo.WithOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, t.Context(ci), nil
+}
+
+// editInEditor opens the given content in the editor configured in $EDITOR
+// (falling back to vi) and passes the edited content to apply. The pattern
+// is used for the name of the temporary file (see ioutil.TempFile). As the
+// content may contain secrets, the file is only written to a private directory
+// that is removed when editInEditor returns. If apply fails, the error is
+// printed and the editor can be opened again to fix the content.
+func editInEditor(content []byte, pattern string, apply func(edited []byte) error) error {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) < 1 {
+		editor = []string{"vi"}
+	}
+
+	dir, err := ioutil.TempDir("", "kusible-edit-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	err = os.Chmod(dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dir, pattern)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	for {
+		cmd := exec.Command(editor[0], append(editor[1:], file.Name())...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		err = cmd.Run()
+		if err != nil {
+			return fmt.Errorf("editor %s failed: %s", editor[0], err)
+		}
+
+		edited, err := ioutil.ReadFile(file.Name())
+		if err != nil {
+			return err
+		}
+
+		err = apply(edited)
+		if err == nil {
+			return nil
+		}
+		if !askReopenEditor(err) {
+			return fmt.Errorf("%s (changes discarded)", err)
+		}
+	}
+}
+
+// askReopenEditor prints the given error and asks if the editor should be
+// opened again. Anything but yes (including a closed stdin) is a no.
+func askReopenEditor(err error) bool {
+	fmt.Fprintf(os.Stderr, "%s\nOpen the editor again? [y/N] ", err)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
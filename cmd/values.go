@@ -17,6 +17,8 @@ limitations under the License.
 package cmd
 
 import (
+	"strings"
+
 	"github.com/bedag/kusible/pkg/printer"
 	"github.com/bedag/kusible/pkg/values"
 	"github.com/spf13/cobra"
@@ -38,21 +40,42 @@ func newValuesCmd(c *Cli) *cobra.Command {
 		RunE:                  c.wrap(runValues),
 	}
 	addEjsonFlags(cmd)
+	addSopsFlags(cmd)
 	addEvalFlags(cmd)
 	addGroupsFlags(cmd)
+	addMergeModeFlags(cmd)
+	addTemplatesFlags(cmd)
 	addOutputFlags(cmd)
+	addExplainFlags(cmd)
+	addExtraVarsFlags(cmd)
 
+	cmd.AddCommand(
+		newValuesDiffCmd(c),
+	)
 	return cmd
 }
 
 func runValues(c *Cli, cmd *cobra.Command, args []string) error {
 	groups := args
-	groupVarsDir := c.viper.GetString("group-vars-dir")
-	skipEval := c.viper.GetBool("skip-eval")
-
-	ejsonSettings := getEjsonSettings(c)
+	groupVarsDirs, err := getGroupVarsDirs(c)
+	if err != nil {
+		return err
+	}
+	extraVars, err := getExtraVars(c)
+	if err != nil {
+		return err
+	}
+	options := values.Options{
+		SkipEval:  c.viper.GetBool("skip-eval"),
+		Ejson:     getEjsonSettings(c),
+		Sops:      getSopsSettings(c),
+		MergeMode: c.viper.GetString("merge-mode"),
+		Templates: c.viper.GetBool("enable-templates"),
+		ExtraVars: extraVars,
+		Overlays:  groupVarsDirs[1:],
+	}
 
-	values, err := values.New(groupVarsDir, groups, skipEval, ejsonSettings)
+	values, err := values.New(groupVarsDirs[0], groups, options)
 	if err != nil {
 		log.WithFields(log.Fields{
 			"error": err.Error(),
@@ -60,6 +83,15 @@ func runValues(c *Cli, cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	c.Log.WithFields(log.Fields{
+		"files": strings.Join(values.Files(), " "),
+	}).Debug("Ordered list of merged files.")
+
+	if c.viper.GetBool("explain") {
+		provenances := values.Explain(nil, c.viper.GetString("path"))
+		return c.output(explainQueue("", provenances))
+	}
+
 	printFn := func(fields []string) map[string]interface{} {
 		all := values.Map()
 		if len(fields) < 1 {
@@ -80,3 +112,39 @@ func runValues(c *Cli, cmd *cobra.Command, args []string) error {
 
 	return c.output(printerQueue)
 }
+
+// explainQueue creates a printer job for each given value provenance
+func explainQueue(entry string, provenances []values.Provenance) printer.Queue {
+	printerQueue := printer.Queue{}
+	for _, provenance := range provenances {
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		provenance := provenance
+
+		job := printer.NewJob(func(fields []string) map[string]interface{} {
+			defaultResult := map[string]interface{}{
+				"path":      provenance.Path,
+				"file":      provenance.File,
+				"overrides": provenance.Overrides,
+				"spruce":    provenance.Spruce,
+				"value":     provenance.Value,
+			}
+			if entry != "" {
+				defaultResult["entry"] = entry
+			}
+
+			if len(fields) < 1 {
+				return defaultResult
+			}
+
+			result := map[string]interface{}{}
+			for _, field := range fields {
+				if val, ok := defaultResult[field]; ok {
+					result[field] = val
+				}
+			}
+			return result
+		})
+		printerQueue = append(printerQueue, job)
+	}
+	return printerQueue
+}
@@ -0,0 +1,280 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/bedag/kusible/internal/wrapper/spruce"
+	"github.com/bedag/kusible/pkg/printer"
+	"github.com/bedag/kusible/pkg/source"
+	"github.com/bedag/kusible/pkg/values"
+	"github.com/bedag/kusible/pkg/wrapper/git"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newValuesDiffCmd(c *Cli) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "diff [ENTRY ENTRY | filter]",
+		Short: "Show how the values of inventory entries differ",
+		Long: `Compare the compiled values of inventory entries.
+
+	With two entries as arguments, the values of the first entry are
+	compared with the values of the second entry.
+
+	With --against, the values of all entries matched by filter (default:
+	all entries) are compared between the given git revision or directory
+	(old) and the current directory (new). Paths given with --inventory,
+	--group-vars-dir, --host-vars-dir and --playbook are resolved relative
+	to the revision / directory if they are relative.
+
+	With --playbook, the rendered playbooks are compared as well.`,
+		Args:                  cobra.MaximumNArgs(2),
+		TraverseChildren:      true,
+		DisableFlagsInUseLine: true,
+		RunE:                  c.wrap(runValuesDiff),
+	}
+	addInventoryFlags(cmd)
+	addGroupsFlags(cmd)
+	addMergeModeFlags(cmd)
+	addTemplatesFlags(cmd)
+	addHostVarsFlags(cmd)
+	addExtraVarsFlags(cmd)
+	addSkipClusterInventoryFlags(cmd)
+	addClusterInventoryFromFlags(cmd)
+	addFactsFlags(cmd)
+	addWorkersFlags(cmd)
+	cmd.Flags().String("against", "", "Git revision or directory the current values are compared with")
+	cmd.Flags().String("playbook", "", "Also compare the given rendered playbook")
+
+	return cmd
+}
+
+func runValuesDiff(c *Cli, cmd *cobra.Command, args []string) error {
+	against := c.viper.GetString("against")
+
+	if against == "" {
+		if len(args) != 2 {
+			return fmt.Errorf("either two inventory entries or --against are required")
+		}
+		filter := fmt.Sprintf("(?:%s|%s)", regexp.QuoteMeta(args[0]), regexp.QuoteMeta(args[1]))
+		docs, err := loadValuesDiffDocs(c, "", filter)
+		if err != nil {
+			return err
+		}
+		for _, name := range args {
+			if _, ok := docs[name]; !ok {
+				return fmt.Errorf("inventory entry %s not found", name)
+			}
+		}
+
+		changes := values.Diff(docs[args[0]], docs[args[1]])
+		printerQueue := printer.Queue{valuesDiffJob(args[1], args[0], args[1], changes)}
+		return c.output(printerQueue)
+	}
+
+	if len(args) > 1 {
+		return fmt.Errorf("only a single filter can be given with --against")
+	}
+	filter := ".*"
+	if len(args) > 0 {
+		filter = args[0]
+	}
+
+	root := against
+	if stat, err := os.Stat(against); err != nil || !stat.IsDir() {
+		root, err = ioutil.TempDir("", "kusible-diff-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(root)
+
+		c.Log.WithFields(logrus.Fields{
+			"revision": against,
+			"dir":      root,
+		}).Debug("Exporting git revision.")
+
+		err = git.Export(".", against, root)
+		if err != nil {
+			c.Log.WithFields(logrus.Fields{
+				"revision": against,
+				"error":    err.Error(),
+			}).Error("Failed to export git revision.")
+			return err
+		}
+	}
+
+	oldDocs, err := loadValuesDiffDocs(c, root, filter)
+	if err != nil {
+		return err
+	}
+	newDocs, err := loadValuesDiffDocs(c, "", filter)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range oldDocs {
+		names = append(names, name)
+	}
+	for name := range newDocs {
+		if _, ok := oldDocs[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	printerQueue := printer.Queue{}
+	affected := []string{}
+	for _, name := range names {
+		changes := values.Diff(oldDocs[name], newDocs[name])
+		if len(changes) < 1 {
+			continue
+		}
+		affected = append(affected, name)
+		printerQueue = append(printerQueue, valuesDiffJob(name, against, "", changes))
+	}
+
+	summary := printer.NewJob(func(fields []string) map[string]interface{} {
+		return map[string]interface{}{
+			"summary": map[string]interface{}{
+				"entries":  len(names),
+				"affected": affected,
+			},
+		}
+	})
+	printerQueue = append(printerQueue, summary)
+
+	return c.output(printerQueue)
+}
+
+/*
+loadValuesDiffDocs returns the evaluated values (and the rendered playbook if
+requested) of all entries matched by filter. Relative paths are resolved
+relative to root.
+*/
+func loadValuesDiffDocs(c *Cli, root string, filter string) (map[string]map[string]interface{}, error) {
+	skipClusterInv := c.viper.GetBool("skip-cluster-inventory")
+	skipEval := c.viper.GetBool("skip-eval")
+	gatherFacts := getGatherFacts(c)
+	playbookFile := rebasePath(root, c.viper.GetString("playbook"))
+
+	inv, err := loadInventoryFromPath(c, rebasePath(root, c.viper.GetString("inventory")), skipClusterInv)
+	if err != nil {
+		return nil, err
+	}
+
+	options, err := getValuesOptions(c)
+	if err != nil {
+		return nil, err
+	}
+	options.HostVarsDir = rebasePath(root, options.HostVarsDir)
+	groupVarsDirs := []string{}
+	for _, dir := range c.viper.GetStringSlice("group-vars-dir") {
+		groupVarsDirs = append(groupVarsDirs, rebasePath(root, dir))
+	}
+	targets, err := loadTargetsWithOptions(c, filter, inv, groupVarsDirs, options)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]map[string]interface{}{}
+	for name, target := range targets.Targets() {
+		data, ctx, err := entryValues(target, skipClusterInv, gatherFacts)
+		if err != nil {
+			return nil, err
+		}
+		err = spruce.EvalWithContext(&data, skipEval, []string{}, ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate values of %s: %s", name, err)
+		}
+		result[name] = map[string]interface{}{
+			"values": data,
+		}
+	}
+
+	if playbookFile != "" {
+		playbooks, err := loadPlaybooksWithTargets(c, []string{playbookFile}, targets, playbookOptions(c))
+		if err != nil {
+			return nil, err
+		}
+		for name, playbook := range playbooks {
+			playbookMap, err := playbook.Map(skipEval)
+			if err != nil {
+				return nil, err
+			}
+			result[name]["playbook"] = playbookMap
+		}
+	}
+
+	return result, nil
+}
+
+// rebasePath resolves path relative to root, unless path is absolute
+func rebasePath(root string, path string) string {
+	if root == "" || path == "" || filepath.IsAbs(path) || source.IsRemote(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
+
+// valuesDiffJob creates a printer job for the changes of a single entry
+func valuesDiffJob(entry string, from string, to string, changes []values.Change) printer.Printable {
+	return printer.NewJob(func(fields []string) map[string]interface{} {
+		resultChanges := []map[string]interface{}{}
+		for _, change := range changes {
+			resultChange := map[string]interface{}{
+				"path": change.Path,
+				"type": change.Type,
+			}
+			if change.Type != values.ChangeAdded {
+				resultChange["old"] = change.Old
+			}
+			if change.Type != values.ChangeRemoved {
+				resultChange["new"] = change.New
+			}
+			resultChanges = append(resultChanges, resultChange)
+		}
+
+		defaultResult := map[string]interface{}{
+			"entry":   entry,
+			"from":    from,
+			"changes": resultChanges,
+		}
+		if to != "" {
+			defaultResult["to"] = to
+		}
+
+		if len(fields) < 1 {
+			return defaultResult
+		}
+
+		result := map[string]interface{}{}
+		for _, field := range fields {
+			if val, ok := defaultResult[field]; ok {
+				result[field] = val
+			}
+		}
+		return result
+	})
+}
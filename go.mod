@@ -4,8 +4,10 @@ go 1.15
 
 require (
 	github.com/Luzifer/go-openssl/v3 v3.1.0
+	github.com/Masterminds/sprig/v3 v3.2.0
 	github.com/Shopify/ejson v1.2.2
 	github.com/aws/aws-sdk-go v1.36.29
+	github.com/evanphx/json-patch v4.9.0+incompatible
 	github.com/gabriel-vasile/mimetype v1.1.2
 	github.com/geofffranks/simpleyaml v0.0.0-20161109204137-c9320f076de5
 	github.com/geofffranks/spruce v1.27.0
@@ -19,11 +21,14 @@ require (
 	github.com/olekukonko/tablewriter v0.0.2
 	github.com/pborman/ansi v1.0.0
 	github.com/pkg/errors v0.9.1
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/cobra v1.1.1
 	github.com/spf13/pflag v1.0.5
 	github.com/spf13/viper v1.7.1
+	github.com/starkandwayne/goutils v0.0.0-20190115202530-896b8a6904be
 	github.com/stretchr/testify v1.7.0
+	gopkg.in/yaml.v3 v3.0.0-20200506231410-2ff61e1afc86
 	gotest.tools v2.2.0+incompatible
 	helm.sh/helm/v3 v3.5.0
 	k8s.io/api v0.20.1
@@ -0,0 +1,102 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+type tarEntry struct {
+	name    string
+	dir     bool
+	content string
+	mode    int64
+}
+
+func newTar(t *testing.T, entries []tarEntry) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	w := tar.NewWriter(buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: entry.mode, Typeflag: tar.TypeReg, Size: int64(len(entry.content))}
+		if entry.dir {
+			header.Typeflag = tar.TypeDir
+			header.Size = 0
+		}
+		assert.NilError(t, w.WriteHeader(header))
+		if !entry.dir {
+			_, err := w.Write([]byte(entry.content))
+			assert.NilError(t, err)
+		}
+	}
+	assert.NilError(t, w.Close())
+	return buf
+}
+
+func TestUntar(t *testing.T) {
+	tests := map[string]struct {
+		entries []tarEntry
+		files   map[string]string
+		err     string
+	}{
+		"files": {
+			entries: []tarEntry{
+				{name: "./", dir: true, mode: 0755},
+				{name: "group_vars/", dir: true, mode: 0755},
+				{name: "group_vars/all.yml", content: "vars: {}\n", mode: 0644},
+				{name: "nested/dir/playbook.yml", content: "plays: []\n", mode: 0600},
+			},
+			files: map[string]string{
+				"group_vars/all.yml":      "vars: {}\n",
+				"nested/dir/playbook.yml": "plays: []\n",
+			},
+		},
+		"outside": {
+			entries: []tarEntry{
+				{name: "../evil.yml", content: "x", mode: 0644},
+			},
+			err: "invalid path in archive: ../evil.yml",
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmp, err := ioutil.TempDir("", "kusible-untar-")
+			assert.NilError(t, err)
+			defer os.RemoveAll(tmp)
+			dir := filepath.Join(tmp, "result")
+
+			err = Untar(newTar(t, tc.entries), dir)
+			if tc.err != "" {
+				assert.Error(t, err, tc.err)
+				return
+			}
+			assert.NilError(t, err)
+			for file, content := range tc.files {
+				data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+				assert.NilError(t, err)
+				assert.Equal(t, content, string(data))
+			}
+		})
+	}
+}
@@ -18,15 +18,26 @@ package spruce
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/bedag/kusible/internal/third_party/deinterface"
 	"github.com/geofffranks/simpleyaml"
 	"github.com/geofffranks/spruce"
-	"github.com/mitchellh/mapstructure"
 	"github.com/pborman/ansi"
 	"sigs.k8s.io/yaml"
 )
 
+// spruceMutex serializes all merges and evaluations. spruce keeps the paths
+// of (( prune )) and (( sort )) operators in package globals, concurrent
+// evaluations would race on them and apply the operators of each other.
+var spruceMutex sync.Mutex
+
 func stripAnsiError(err error) error {
 	if err != nil {
 		strippedError, _ := ansi.Strip([]byte(err.Error()))
@@ -35,51 +46,283 @@ func stripAnsiError(err error) error {
 	return nil
 }
 
+// operatorErrorPattern matches the errors spruce reports for failed operators
+var operatorErrorPattern = regexp.MustCompile(`^\$\.(\S+): (?s:(.*))$`)
+
+// newEvalError converts the errors reported by the spruce evaluator
+// into an EvalError
+func newEvalError(err error) error {
+	var errs []error
+	switch e := err.(type) {
+	case spruce.MultiError:
+		errs = e.Errors
+	case *spruce.MultiError:
+		errs = e.Errors
+	default:
+		return stripAnsiError(err)
+	}
+
+	result := &EvalError{}
+	for _, e := range errs {
+		msg := stripAnsiError(e).Error()
+		if match := operatorErrorPattern.FindStringSubmatch(msg); match != nil {
+			result.Errors = append(result.Errors, OperatorError{Path: match[1], Message: match[2]})
+			continue
+		}
+		result.Errors = append(result.Errors, OperatorError{Message: msg})
+	}
+	return result
+}
+
+func (e *EvalError) Error() string {
+	lines := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		lines = append(lines, fmt.Sprintf(" - %s\n", err.Error()))
+	}
+	sort.Strings(lines)
+	return fmt.Sprintf("%d error(s) detected:\n%s\n", len(e.Errors), strings.Join(lines, ""))
+}
+
+func (e OperatorError) Error() string {
+	msg := e.Message
+	if e.Path != "" {
+		msg = fmt.Sprintf("$.%s: %s", e.Path, msg)
+	}
+	if e.Source != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Source)
+	}
+	return msg
+}
+
 // Eval is a wrapper around the Evaluator of https://github.com/geofffranks/spruce
 // that handles the necessary type conversion
 func Eval(data *map[string]interface{}, skipEval bool, pruneKeys []string) error {
-	// To function, the spruce evaluator expects its data in a very specific
-	// structure, which (from what I understand right now), will only be created
-	// by https://github.com/geofffranks/simpleyaml/blob/master/simpleyaml.go and
-	// the yaml library it uses.
-	// To make the evaluator work as expected, we have to convert the input
-	// datastructure to the expected format (Marshal with yaml, unmarshal with simpleyaml)
-	// eval it and then convert everything back.
-
-	// convert to expected datastructure
-	raw, err := yaml.Marshal(data)
-	if err != nil {
-		return err
-	}
-	y, err := simpleyaml.NewYaml(raw)
+	return EvalWithContext(data, skipEval, pruneKeys, nil)
+}
+
+// EvalWithContext is the same as Eval() but makes the given context available
+// to the kusible spruce operators (see operators.go)
+func EvalWithContext(data *map[string]interface{}, skipEval bool, pruneKeys []string, ctx *Context) error {
+	spruceMutex.Lock()
+	defer spruceMutex.Unlock()
+	return eval(data, skipEval, pruneKeys, ctx)
+}
+
+// MergeEval merges the given documents with the merge engine of
+// https://github.com/geofffranks/spruce in order (later documents override
+// earlier ones), supporting the spruce array operators like (( append )) or
+// (( merge on name )), and evaluates the result like EvalWithContext().
+// data is set to the merged documents before the evaluation and replaced
+// with the evaluated result if it succeeds.
+// (( prune )) and (( sort )) operators overridden while merging are only
+// applied by the evaluation following the merge, which is why both happen
+// in one call.
+func MergeEval(data *map[string]interface{}, docs []map[string]interface{}, skipEval bool, pruneKeys []string, ctx *Context) error {
+	spruceMutex.Lock()
+	defer spruceMutex.Unlock()
+
+	merged, err := merge(docs...)
 	if err != nil {
 		return err
 	}
-	doc, err := y.Map()
+	*data = merged
+	return eval(data, skipEval, pruneKeys, ctx)
+}
+
+func eval(data *map[string]interface{}, skipEval bool, pruneKeys []string, ctx *Context) error {
+	doc, err := toTree(data)
 	if err != nil {
 		return err
 	}
 
 	// eval
 	evaluator := &spruce.Evaluator{Tree: doc, SkipEval: skipEval}
+	if ctx != nil {
+		contexts.set(evaluator, ctx)
+		defer contexts.remove(evaluator)
+	}
 	err = evaluator.Run(pruneKeys, nil)
 	if err != nil {
-		return stripAnsiError(err)
+		return newEvalError(err)
 	}
 
-	// convert back
-	di, err := deinterface.Map(evaluator.Tree, true)
+	return fromTree(evaluator.Tree, data)
+}
+
+func merge(docs ...map[string]interface{}) (map[string]interface{}, error) {
+	trees := make([]map[interface{}]interface{}, 0, len(docs))
+	for i := range docs {
+		tree, err := toTree(&docs[i])
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, tree)
+	}
 
-	decoderConfig := &mapstructure.DecoderConfig{ZeroFields: true, Result: data}
-	decoder, err := mapstructure.NewDecoder(decoderConfig)
+	merged, err := spruce.Merge(trees...)
 	if err != nil {
-		return err
+		return nil, stripAnsiError(err)
 	}
 
-	err = decoder.Decode(di)
+	result := map[string]interface{}{}
+	err = fromTree(merged, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// toTree converts data to the structure expected by spruce.
+// To function, spruce expects its data in a very specific
+// structure, which (from what I understand right now), will only be created
+// by https://github.com/geofffranks/simpleyaml/blob/master/simpleyaml.go and
+// the yaml library it uses: maps are map[interface{}]interface{} and numbers
+// are int, int64 or uint64 if they are integral and float64 otherwise.
+// The data is converted directly, only values of unknown types are
+// converted by marshalling them to yaml and parsing them with simpleyaml.
+func toTree(data *map[string]interface{}) (map[interface{}]interface{}, error) {
+	tree, err := toTreeValue(*data)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		return map[interface{}]interface{}{}, nil
+	}
+	return tree.(map[interface{}]interface{}), nil
+}
+
+func toTreeValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil, string, bool:
+		return v, nil
+	case map[string]interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		result := make(map[interface{}]interface{}, len(v))
+		for key, element := range v {
+			converted, err := toTreeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case []interface{}:
+		if v == nil {
+			return nil, nil
+		}
+		result := make([]interface{}, len(v))
+		for i, element := range v {
+			converted, err := toTreeValue(element)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = converted
+		}
+		return result, nil
+	case int:
+		return v, nil
+	case int8:
+		return int(v), nil
+	case int16:
+		return int(v), nil
+	case int32:
+		return int(v), nil
+	case int64:
+		return toTreeInt(v), nil
+	case uint:
+		return toTreeUint(uint64(v)), nil
+	case uint8:
+		return int(v), nil
+	case uint16:
+		return int(v), nil
+	case uint32:
+		return toTreeInt(int64(v)), nil
+	case uint64:
+		return toTreeUint(v), nil
+	case float32:
+		return toTreeFloat32(v), nil
+	case float64:
+		return toTreeFloat(v), nil
+	default:
+		return toTreeYAML(v)
+	}
+}
+
+// toTreeInt returns the type yaml uses for the given integer
+func toTreeInt(v int64) interface{} {
+	if v == int64(int(v)) {
+		return int(v)
+	}
+	return v
+}
+
+// toTreeUint returns the type yaml uses for the given unsigned integer
+func toTreeUint(v uint64) interface{} {
+	if v <= math.MaxInt64 {
+		return toTreeInt(int64(v))
+	}
+	return v
+}
+
+// toTreeFloat returns the type yaml uses for the given float. Floats are
+// marshalled as json numbers first, which writes integral floats below
+// 1e21 as integers (using the shortest representation of the float).
+func toTreeFloat(v float64) interface{} {
+	if math.IsNaN(v) || math.IsInf(v, 0) || v != math.Trunc(v) || math.Abs(v) >= 1e21 {
+		return v
+	}
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return toTreeInt(i)
+	}
+	if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return u
+	}
+	return v
+}
+
+// toTreeFloat32 returns the type yaml uses for the given float32. Floats
+// are marshalled using the shortest representation of their own size,
+// so float32 values must not be widened before formatting them.
+func toTreeFloat32(v float32) interface{} {
+	f, err := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+	if err != nil {
+		return toTreeFloat(float64(v))
+	}
+	return toTreeFloat(f)
+}
+
+// toTreeYAML converts a single value by marshalling it to yaml and
+// parsing the result with simpleyaml
+func toTreeYAML(value interface{}) (interface{}, error) {
+	raw, err := yaml.Marshal(map[string]interface{}{"value": value})
+	if err != nil {
+		return nil, err
+	}
+	y, err := simpleyaml.NewYaml(raw)
+	if err != nil {
+		return nil, err
+	}
+	m, err := y.Map()
+	if err != nil {
+		return nil, err
+	}
+	return m["value"], nil
+}
+
+// fromTree converts a spruce tree back to data
+func fromTree(tree map[interface{}]interface{}, data *map[string]interface{}) error {
+	di, err := deinterface.Map(tree, true)
 	if err != nil {
 		return err
 	}
 
+	result, ok := di.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unexpected spruce result type %T", di)
+	}
+	*data = result
 	return nil
 }
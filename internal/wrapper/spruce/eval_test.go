@@ -17,9 +17,17 @@ limitations under the License.
 package spruce
 
 import (
+	"fmt"
+	"math"
+	"sort"
 	"testing"
 
+	"github.com/bedag/kusible/internal/third_party/deinterface"
+	"github.com/geofffranks/simpleyaml"
+	"github.com/geofffranks/spruce"
+	"github.com/mitchellh/mapstructure"
 	"gotest.tools/assert"
+	"sigs.k8s.io/yaml"
 )
 
 func TestEval(t *testing.T) {
@@ -89,3 +97,279 @@ func TestEval(t *testing.T) {
 		})
 	}
 }
+
+func TestEvalError(t *testing.T) {
+	data := map[string]interface{}{
+		"key1": "(( grab missing ))",
+		"nested": map[string]interface{}{
+			"key2": "(( concat key3 \"-\" ))",
+		},
+	}
+
+	err := Eval(&data, false, []string{})
+	evalErr, ok := err.(*EvalError)
+	assert.Assert(t, ok)
+
+	paths := []string{}
+	for _, opErr := range evalErr.Errors {
+		paths = append(paths, opErr.Path)
+		assert.Assert(t, opErr.Message != "")
+	}
+	sort.Strings(paths)
+	assert.DeepEqual(t, []string{"key1", "nested.key2"}, paths)
+
+	evalErr.Errors[0].Source = "values.yml:3"
+	assert.ErrorContains(t, evalErr, "2 error(s) detected")
+	assert.ErrorContains(t, evalErr, "(values.yml:3)")
+}
+
+func TestMergeEval(t *testing.T) {
+	tests := map[string]struct {
+		docs     []map[string]interface{}
+		err      bool
+		expected map[string]interface{}
+	}{
+		"override": {
+			docs: []map[string]interface{}{
+				{"key1": "a", "key2": map[string]interface{}{"a": "a", "b": "a"}},
+				{"key2": map[string]interface{}{"b": "b"}},
+			},
+			expected: map[string]interface{}{"key1": "a", "key2": map[string]interface{}{"a": "a", "b": "b"}},
+		},
+		"append": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{"a", "b"}},
+				{"list": []interface{}{"(( append ))", "c"}},
+			},
+			expected: map[string]interface{}{"list": []interface{}{"a", "b", "c"}},
+		},
+		"prepend": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{"a", "b"}},
+				{"list": []interface{}{"(( prepend ))", "c"}},
+			},
+			expected: map[string]interface{}{"list": []interface{}{"c", "a", "b"}},
+		},
+		"replace": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{map[string]interface{}{"name": "a", "value": "a"}}},
+				{"list": []interface{}{"(( replace ))", map[string]interface{}{"name": "b"}}},
+			},
+			expected: map[string]interface{}{"list": []interface{}{map[string]interface{}{"name": "b"}}},
+		},
+		"merge-on-name": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{map[string]interface{}{"name": "a", "value": "a"}, map[string]interface{}{"name": "b", "value": "b"}}},
+				{"list": []interface{}{map[string]interface{}{"name": "b", "value": "c"}}},
+			},
+			expected: map[string]interface{}{"list": []interface{}{map[string]interface{}{"name": "a", "value": "a"}, map[string]interface{}{"name": "b", "value": "c"}}},
+		},
+		"prune": {
+			docs: []map[string]interface{}{
+				{"key1": "a", "meta": map[string]interface{}{"a": "a"}},
+				{"meta": "(( prune ))"},
+			},
+			expected: map[string]interface{}{"key1": "a"},
+		},
+		"sort": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{"b", "a"}},
+				{"list": "(( sort ))"},
+			},
+			expected: map[string]interface{}{"list": []interface{}{"a", "b"}},
+		},
+		"keep-operators": {
+			docs: []map[string]interface{}{
+				{"key1": "test"},
+				{"key2": "(( grab key1 ))"},
+			},
+			expected: map[string]interface{}{"key1": "test", "key2": "(( grab key1 ))"},
+		},
+		"error": {
+			docs: []map[string]interface{}{
+				{"list": []interface{}{map[string]interface{}{"name": "a"}}},
+				{"list": []interface{}{"(( merge on id ))", map[string]interface{}{"name": "b"}}},
+			},
+			err:      true,
+			expected: nil,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			var result map[string]interface{}
+			err := MergeEval(&result, tc.docs, true, []string{}, nil)
+			assert.Equal(t, tc.err, err != nil)
+			assert.DeepEqual(t, tc.expected, result)
+		})
+	}
+}
+
+// toTreeRoundTrip is the reference conversion marshalling the whole
+// document to yaml and parsing it with simpleyaml
+func toTreeRoundTrip(data *map[string]interface{}) (map[interface{}]interface{}, error) {
+	raw, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	y, err := simpleyaml.NewYaml(raw)
+	if err != nil {
+		return nil, err
+	}
+	return y.Map()
+}
+
+// fromTreeMapstructure is the reference conversion decoding the
+// deinterfaced tree with mapstructure
+func fromTreeMapstructure(tree map[interface{}]interface{}, data *map[string]interface{}) error {
+	di, err := deinterface.Map(tree, true)
+	if err != nil {
+		return err
+	}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{ZeroFields: true, Result: data})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(di)
+}
+
+// evalRoundTrip is the reference implementation of Eval
+func evalRoundTrip(data *map[string]interface{}, skipEval bool, pruneKeys []string) error {
+	doc, err := toTreeRoundTrip(data)
+	if err != nil {
+		return err
+	}
+	evaluator := &spruce.Evaluator{Tree: doc, SkipEval: skipEval}
+	err = evaluator.Run(pruneKeys, nil)
+	if err != nil {
+		return stripAnsiError(err)
+	}
+	return fromTreeMapstructure(evaluator.Tree, data)
+}
+
+func TestToTree(t *testing.T) {
+	tests := map[string]interface{}{
+		"nil":              nil,
+		"string":           "test",
+		"bool string":      "true",
+		"number string":    "1",
+		"empty string":     "",
+		"bool":             true,
+		"int":              1,
+		"int32":            int32(-5),
+		"int64":            int64(math.MaxInt64),
+		"uint64":           uint64(math.MaxUint64),
+		"integral float":   float64(42),
+		"negative float":   float64(-42),
+		"negative zero":    math.Copysign(0, -1),
+		"float":            1.5,
+		"float32":          float32(1.1),
+		"integral float32": float32(16777216),
+		"huge float32":     float32(3e38),
+		"small float":      1e-7,
+		"huge float":       1e21,
+		"max int float":    float64(1 << 63),
+		"large float":      -1e19,
+		"inexact float":    float64(1 << 62),
+		"empty map":        map[string]interface{}{},
+		"empty list":       []interface{}{},
+		"nested": map[string]interface{}{
+			"list": []interface{}{1.0, "a", map[string]interface{}{"b": 2.5}, []interface{}{nil}},
+		},
+		"string list":  []string{"a", "b"},
+		"string map":   map[string]string{"a": "b"},
+		"typed map":    map[string]int{"a": 1},
+		"operator":     "(( grab a.b ))",
+		"nil map":      map[string]interface{}(nil),
+		"multi line":   "a\nb\n",
+		"special keys": map[string]interface{}{"1": "a", "true": "b", "": "c"},
+	}
+
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := map[string]interface{}{"key": value}
+			expected, err := toTreeRoundTrip(&data)
+			assert.NilError(t, err)
+			got, err := toTree(&data)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, expected, got)
+
+			var expectedData, gotData map[string]interface{}
+			assert.NilError(t, fromTreeMapstructure(expected, &expectedData))
+			assert.NilError(t, fromTree(got, &gotData))
+			assert.DeepEqual(t, expectedData, gotData)
+		})
+	}
+}
+
+// largeTree generates a values tree similar to merged group vars with
+// the given number of groups
+func largeTree(groups int) map[string]interface{} {
+	vars := map[string]interface{}{}
+	for i := 0; i < groups; i++ {
+		group := map[string]interface{}{}
+		for j := 0; j < 20; j++ {
+			group[fmt.Sprintf("key-%d", j)] = map[string]interface{}{
+				"string": fmt.Sprintf("value-%d-%d", i, j),
+				"int":    float64(i * j),
+				"float":  float64(i) + 0.5,
+				"bool":   j%2 == 0,
+				"list":   []interface{}{"a", float64(j), map[string]interface{}{"name": "x", "value": "y"}},
+			}
+		}
+		group["ref"] = fmt.Sprintf("(( grab vars.group-%d.key-1.string ))", i)
+		group["concat"] = fmt.Sprintf("(( concat vars.group-%d.key-2.string \"-suffix\" ))", i)
+		vars[fmt.Sprintf("group-%d", i)] = group
+	}
+	return map[string]interface{}{"vars": vars}
+}
+
+func TestEvalEquivalence(t *testing.T) {
+	for _, skipEval := range []bool{false, true} {
+		expected := largeTree(20)
+		got := largeTree(20)
+
+		assert.NilError(t, evalRoundTrip(&expected, skipEval, []string{"vars.group-0"}))
+		assert.NilError(t, Eval(&got, skipEval, []string{"vars.group-0"}))
+		assert.DeepEqual(t, expected, got)
+	}
+}
+
+func BenchmarkEval(b *testing.B) {
+	for _, groups := range []int{10, 100} {
+		b.Run(fmt.Sprintf("direct-%d", groups), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				data := largeTree(groups)
+				if err := Eval(&data, false, []string{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("yaml-%d", groups), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				data := largeTree(groups)
+				if err := evalRoundTrip(&data, false, []string{}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkToTree(b *testing.B) {
+	data := largeTree(100)
+	b.Run("direct", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := toTree(&data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("yaml", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := toTreeRoundTrip(&data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
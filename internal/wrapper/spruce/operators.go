@@ -0,0 +1,265 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spruce
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/geofffranks/spruce"
+	"github.com/starkandwayne/goutils/tree"
+	"sigs.k8s.io/yaml"
+)
+
+// contexts holds the contexts of the running evaluations
+var contexts = &contextRegistry{contexts: map[*spruce.Evaluator]*Context{}}
+
+/*
+The kusible specific spruce operators
+
+ * (( kusible_entry )): name of the inventory entry
+ * (( kusible_groups )): list of groups of the inventory entry
+ * (( kusible_in_group "group" )): true if the entry is member of the given group
+ * (( ejson_file "path" "key" )): value of the (dot separated) key of the given
+   decrypted ejson file, the whole file if no key is given
+ * (( cluster_inventory "key" )): value of the (dot separated) key of the cluster
+   inventory, the whole cluster inventory if no key is given
+
+All arguments can be literals or references.
+*/
+func init() {
+	spruce.RegisterOp("kusible_entry", kusibleOperator{name: "kusible_entry", contexts: contexts, run: runKusibleEntry})
+	spruce.RegisterOp("kusible_groups", kusibleOperator{name: "kusible_groups", contexts: contexts, run: runKusibleGroups})
+	spruce.RegisterOp("kusible_in_group", kusibleOperator{name: "kusible_in_group", contexts: contexts, run: runKusibleInGroup})
+	spruce.RegisterOp("ejson_file", kusibleOperator{name: "ejson_file", contexts: contexts, run: runEjsonFile})
+	spruce.RegisterOp("cluster_inventory", kusibleOperator{name: "cluster_inventory", contexts: contexts, run: runClusterInventory})
+}
+
+// kusibleOperator implements spruce.Operator for operators that only need
+// the evaluated arguments and the context of the evaluation
+type kusibleOperator struct {
+	name     string
+	contexts *contextRegistry
+	run      func(ctx *Context, here string, args []interface{}) (interface{}, error)
+}
+
+func (kusibleOperator) Setup() error {
+	return nil
+}
+
+func (kusibleOperator) Phase() spruce.OperatorPhase {
+	return spruce.EvalPhase
+}
+
+func (kusibleOperator) Dependencies(_ *spruce.Evaluator, _ []*spruce.Expr, _ []*tree.Cursor, auto []*tree.Cursor) []*tree.Cursor {
+	return auto
+}
+
+func (o kusibleOperator) Run(ev *spruce.Evaluator, args []*spruce.Expr) (*spruce.Response, error) {
+	ctx := o.contexts.get(ev)
+	if ctx == nil {
+		return nil, fmt.Errorf("(( %s )) is only available when evaluating the values of an inventory entry", o.name)
+	}
+
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		value, err := arg.Evaluate(ev.Tree)
+		if err != nil {
+			return nil, fmt.Errorf("(( %s )): %s", o.name, err)
+		}
+		values = append(values, value)
+	}
+
+	here := ""
+	if ev.Here != nil {
+		here = ev.Here.String()
+	}
+	result, err := o.run(ctx, here, values)
+	if err != nil {
+		return nil, fmt.Errorf("(( %s )): %s", o.name, err)
+	}
+	return &spruce.Response{
+		Type:  spruce.Replace,
+		Value: result,
+	}, nil
+}
+
+func runKusibleEntry(ctx *Context, _ string, args []interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("no arguments expected")
+	}
+	if ctx.Entry == "" {
+		return nil, fmt.Errorf("no inventory entry available")
+	}
+	return ctx.Entry, nil
+}
+
+func runKusibleGroups(ctx *Context, _ string, args []interface{}) (interface{}, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("no arguments expected")
+	}
+	result := make([]interface{}, 0, len(ctx.Groups))
+	for _, group := range ctx.Groups {
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+func runKusibleInGroup(ctx *Context, _ string, args []interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("exactly one group expected")
+	}
+	group, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("group must be a string")
+	}
+	for _, g := range ctx.Groups {
+		if g == group {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func runEjsonFile(ctx *Context, here string, args []interface{}) (interface{}, error) {
+	path, key, err := pathAndKey(args)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) && ctx.BaseDir != nil {
+		if dir := ctx.BaseDir(here); dir != "" {
+			path = filepath.Join(dir, path)
+		}
+	}
+
+	settings := ctx.Ejson
+	settings.Strict = true
+	raw, err := ejson.ReadFile(path, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var data map[string]interface{}
+	err = yaml.Unmarshal(raw, &data)
+	if err != nil {
+		return nil, err
+	}
+	delete(data, "_public_key")
+
+	return lookup(data, key)
+}
+
+func runClusterInventory(ctx *Context, _ string, args []interface{}) (interface{}, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("at most one key expected")
+	}
+	if ctx.ClusterInventory == nil {
+		return nil, fmt.Errorf("no cluster inventory available")
+	}
+
+	key := ""
+	if len(args) > 0 {
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("key must be a string")
+		}
+		key = s
+	}
+	return lookup(ctx.ClusterInventory, key)
+}
+
+func (r *contextRegistry) set(ev *spruce.Evaluator, ctx *Context) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.contexts[ev] = ctx
+}
+
+func (r *contextRegistry) remove(ev *spruce.Evaluator) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.contexts, ev)
+}
+
+func (r *contextRegistry) get(ev *spruce.Evaluator) *Context {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.contexts[ev]
+}
+
+// pathAndKey returns the path and the optional key given as arguments
+func pathAndKey(args []interface{}) (string, string, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return "", "", fmt.Errorf("a path and an optional key expected")
+	}
+	strs := []string{"", ""}
+	for i, arg := range args {
+		s, ok := arg.(string)
+		if !ok {
+			return "", "", fmt.Errorf("arguments must be strings")
+		}
+		strs[i] = s
+	}
+	return strs[0], strs[1], nil
+}
+
+// lookup converts data to a spruce tree and returns the value at the
+// given dot separated key. List elements can be addressed by index
+// or by the value of their name key.
+func lookup(data map[string]interface{}, key string) (interface{}, error) {
+	var current interface{}
+	current, err := toTreeValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return current, nil
+	}
+
+	for _, k := range strings.Split(key, ".") {
+		switch node := current.(type) {
+		case map[interface{}]interface{}:
+			value, ok := node[k]
+			if !ok {
+				return nil, fmt.Errorf("key %s not found", key)
+			}
+			current = value
+		case []interface{}:
+			found := false
+			if i, err := strconv.Atoi(k); err == nil && i >= 0 && i < len(node) {
+				current = node[i]
+				found = true
+			} else {
+				for _, element := range node {
+					if m, ok := element.(map[interface{}]interface{}); ok && m["name"] == k {
+						current = element
+						found = true
+						break
+					}
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("key %s not found", key)
+			}
+		default:
+			return nil, fmt.Errorf("key %s not found", key)
+		}
+	}
+	return current, nil
+}
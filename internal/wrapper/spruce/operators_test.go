@@ -0,0 +1,85 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spruce
+
+import (
+	"testing"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"gotest.tools/assert"
+)
+
+func TestOperators(t *testing.T) {
+	ctx := &Context{
+		Entry:  "cluster-01",
+		Groups: []string{"all", "prod"},
+		ClusterInventory: map[string]interface{}{
+			"region": "eu",
+			"nodes":  map[string]interface{}{"count": float64(3)},
+		},
+		Ejson: ejson.Settings{KeyDirs: []string{"testdata/keydir"}},
+	}
+
+	relCtx := *ctx
+	relCtx.BaseDir = func(path string) string {
+		if path == "result" {
+			return "testdata"
+		}
+		return ""
+	}
+
+	tests := map[string]struct {
+		value    string
+		ctx      *Context
+		err      bool
+		expected interface{}
+	}{
+		"entry":                      {value: "(( kusible_entry ))", ctx: ctx, expected: "cluster-01"},
+		"entry without context":      {value: "(( kusible_entry ))", ctx: nil, err: true},
+		"entry without entry":        {value: "(( kusible_entry ))", ctx: &Context{}, err: true},
+		"groups":                     {value: "(( kusible_groups ))", ctx: ctx, expected: []interface{}{"all", "prod"}},
+		"in group":                   {value: `(( kusible_in_group "prod" ))`, ctx: ctx, expected: true},
+		"not in group":               {value: `(( kusible_in_group "dev" ))`, ctx: ctx, expected: false},
+		"in group reference":         {value: "(( kusible_in_group group ))", ctx: ctx, expected: true},
+		"in group missing arg":       {value: "(( kusible_in_group ))", ctx: ctx, err: true},
+		"ejson file key":             {value: `(( ejson_file "testdata/simple.ejson" "data.dict.dictKey1" ))`, ctx: ctx, expected: "dictValue1"},
+		"ejson file list":            {value: `(( ejson_file "testdata/simple.ejson" "data.array.1" ))`, ctx: ctx, expected: "arrayValue2"},
+		"ejson file":                 {value: `(( ejson_file "testdata/simple.ejson" ))`, ctx: ctx, expected: map[string]interface{}{"data": map[string]interface{}{"array": []interface{}{"arrayValue1", "arrayValue2"}, "dict": map[string]interface{}{"dictKey1": "dictValue1", "dictKey2": "dictValue2"}, "key1": "value1", "key2": "value2"}}},
+		"ejson file missing key":     {value: `(( ejson_file "testdata/simple.ejson" "data.missing" ))`, ctx: ctx, err: true},
+		"ejson file relative":        {value: `(( ejson_file "simple.ejson" "data.key1" ))`, ctx: &relCtx, expected: "value1"},
+		"ejson file not in base dir": {value: `(( ejson_file "testdata/simple.ejson" "data.key1" ))`, ctx: &relCtx, err: true},
+		"ejson file no privkey":      {value: `(( ejson_file "testdata/simple.ejson" "data.key1" ))`, ctx: &Context{}, err: true},
+		"cluster inventory key":      {value: `(( cluster_inventory "nodes.count" ))`, ctx: ctx, expected: 3},
+		"cluster inventory":          {value: "(( cluster_inventory ))", ctx: ctx, expected: map[string]interface{}{"region": "eu", "nodes": map[string]interface{}{"count": 3}}},
+		"cluster inventory missing":  {value: `(( cluster_inventory "missing" ))`, ctx: ctx, err: true},
+		"no cluster inventory":       {value: `(( cluster_inventory "region" ))`, ctx: &Context{}, err: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"group":  "prod",
+				"result": tc.value,
+			}
+			err := EvalWithContext(&data, false, []string{"group"}, tc.ctx)
+			assert.Equal(t, tc.err, err != nil, "%v", err)
+			if !tc.err {
+				assert.DeepEqual(t, map[string]interface{}{"result": tc.expected}, data)
+			}
+		})
+	}
+}
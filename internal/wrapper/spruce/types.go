@@ -0,0 +1,69 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spruce
+
+import (
+	"sync"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/geofffranks/spruce"
+)
+
+// Context holds the kusible specific data available to the kusible
+// spruce operators during an evaluation
+type Context struct {
+	// Entry is the name of the inventory entry
+	Entry string
+	// Groups are the groups of the inventory entry
+	Groups []string
+	// ClusterInventory is the cluster inventory of the entry, nil if
+	// it was not retrieved
+	ClusterInventory map[string]interface{}
+	// Ejson is used to decrypt files read by (( ejson_file ))
+	Ejson ejson.Settings
+	// BaseDir returns the directory relative paths of the (( ejson_file ))
+	// operator at the given dot separated path are resolved against,
+	// usually the directory of the values file defining the operator.
+	// Relative paths are resolved against the working directory if
+	// BaseDir is nil or returns an empty string.
+	BaseDir func(path string) string
+}
+
+// contextRegistry holds the contexts of all running evaluations.
+// The kusible spruce operators are registered globally, so they look up
+// the context of an evaluation by its evaluator.
+type contextRegistry struct {
+	mutex    sync.RWMutex
+	contexts map[*spruce.Evaluator]*Context
+}
+
+// EvalError is returned by Eval() if spruce failed to evaluate
+// at least one operator
+type EvalError struct {
+	Errors []OperatorError
+}
+
+// OperatorError is a single error reported by spruce
+type OperatorError struct {
+	// Path is the dot separated path of the failed operator,
+	// empty if spruce did not report it
+	Path    string
+	Message string
+	// Source optionally describes where the operator was defined
+	// (e.g. file:line), it is appended to the error message
+	Source string
+}
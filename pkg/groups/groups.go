@@ -98,6 +98,33 @@ func Groups(directory string, filter string, limits []string) ([]string, error)
 	return result, nil
 }
 
+/*
+GroupsOfDirectories is the same as Groups() but returns the union of the
+groups of all given directories
+*/
+func GroupsOfDirectories(directories []string, filter string, limits []string) ([]string, error) {
+	groupSet := make(map[string]bool)
+	for _, directory := range directories {
+		groups, err := Groups(directory, filter, []string{})
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			groupSet[group] = true
+		}
+	}
+
+	groups := make([]string, 0, len(groupSet))
+	for group := range groupSet {
+		groups = append(groups, group)
+	}
+
+	if len(limits) > 0 {
+		return LimitGroups(groups, limits)
+	}
+	return groups, nil
+}
+
 /*
 SortedGroups is the same as Groups() but the resulting list is sorted alphabetically
 */
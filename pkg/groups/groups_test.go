@@ -76,3 +76,29 @@ func TestLimitGroups(t *testing.T) {
 	}
 
 }
+
+func TestGroupsOfDirectories(t *testing.T) {
+	tests := map[string]struct {
+		directories []string
+		filter      string
+		limits      []string
+		expected    []string
+	}{
+		"single":    {directories: []string{"testdata"}, filter: ".*", limits: []string{}, expected: []string{"group01", "group02", "group03", "group04", "group05"}},
+		"union":     {directories: []string{"testdata", "testdata/group01"}, filter: ".*", limits: []string{}, expected: []string{"file", "group01", "group02", "group03", "group04", "group05"}},
+		"duplicate": {directories: []string{"testdata", "testdata"}, filter: ".*[23]", limits: []string{}, expected: []string{"group02", "group03"}},
+		"limits":    {directories: []string{"testdata", "testdata/group01"}, filter: ".*", limits: []string{"file", ".*1"}, expected: []string{"file", "group01"}},
+		"empty":     {directories: []string{}, filter: ".*", limits: []string{}, expected: []string{}},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			gotGroups, err := GroupsOfDirectories(tc.directories, tc.filter, tc.limits)
+			assert.NilError(t, err)
+			wantGroups := tc.expected
+			sort.Strings(gotGroups)
+			sort.Strings(wantGroups)
+			assert.DeepEqual(t, wantGroups, gotGroups)
+		})
+	}
+}
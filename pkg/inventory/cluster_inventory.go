@@ -0,0 +1,283 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	invconfig "github.com/bedag/kusible/pkg/inventory/config"
+	"github.com/bedag/kusible/pkg/loader"
+	"github.com/imdario/mergo"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	ClusterInventorySourceConfigMap = "configmap"
+	ClusterInventorySourceSecret    = "secret"
+	ClusterInventorySourceSelector  = "selector"
+	ClusterInventorySourceFile      = "file"
+
+	// default key in the data of configmaps / secrets holding
+	// the cluster inventory
+	defaultClusterInventoryKey = "inventory"
+)
+
+// clusterInventorySource retrieves the data of a single cluster inventory source
+func (e *Entry) clusterInventorySource(source invconfig.ClusterInventorySource) (map[string]interface{}, error) {
+	if source.Namespace == "" {
+		source.Namespace = e.ClusterInventoryConfig().Namespace
+	}
+	if source.Key == "" {
+		source.Key = defaultClusterInventoryKey
+	}
+
+	sourceType := strings.ToLower(source.Type)
+	if sourceType == "" {
+		sourceType = ClusterInventorySourceConfigMap
+	}
+
+	switch sourceType {
+	case ClusterInventorySourceConfigMap:
+		return e.clusterInventoryConfigMap(source)
+	case ClusterInventorySourceSecret:
+		return e.clusterInventorySecret(source)
+	case ClusterInventorySourceSelector:
+		return e.clusterInventorySelector(source)
+	case ClusterInventorySourceFile:
+		return clusterInventoryFile(source)
+	default:
+		return nil, fmt.Errorf("unknown cluster-inventory source type: %s", source.Type)
+	}
+}
+
+func (e *Entry) clusterInventoryConfigMap(source invconfig.ClusterInventorySource) (map[string]interface{}, error) {
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(source.Namespace).Get(context.Background(), source.Name, metav1.GetOptions{})
+	if err != nil {
+		if source.Optional && apierrors.IsNotFound(err) {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("ConfigMap %s/%s: %s", source.Namespace, source.Name, err)
+	}
+
+	rawData, ok := configMap.Data[source.Key]
+	if !ok {
+		return nil, fmt.Errorf("wrong cluster-inventory format: expecting '%s' key in configmap %s/%s data", source.Key, source.Namespace, source.Name)
+	}
+
+	return parseClusterInventory([]byte(rawData))
+}
+
+func (e *Entry) clusterInventorySecret(source invconfig.ClusterInventorySource) (map[string]interface{}, error) {
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := clientset.CoreV1().Secrets(source.Namespace).Get(context.Background(), source.Name, metav1.GetOptions{})
+	if err != nil {
+		if source.Optional && apierrors.IsNotFound(err) {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("Secret %s/%s: %s", source.Namespace, source.Name, err)
+	}
+
+	rawData, ok := secret.Data[source.Key]
+	if !ok {
+		return nil, fmt.Errorf("wrong cluster-inventory format: expecting '%s' key in secret %s/%s data", source.Key, source.Namespace, source.Name)
+	}
+
+	return parseClusterInventory(rawData)
+}
+
+func (e *Entry) clusterInventorySelector(source invconfig.ClusterInventorySource) (map[string]interface{}, error) {
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := clientset.CoreV1().ConfigMaps(source.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: source.Selector})
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMaps %s/%s: %s", source.Namespace, source.Selector, err)
+	}
+
+	configMaps := list.Items
+	sort.Slice(configMaps, func(i, j int) bool { return configMaps[i].Name < configMaps[j].Name })
+
+	result := map[string]interface{}{}
+	for _, configMap := range configMaps {
+		rawData, ok := configMap.Data[source.Key]
+		if !ok {
+			return nil, fmt.Errorf("wrong cluster-inventory format: expecting '%s' key in configmap %s/%s data", source.Key, configMap.Namespace, configMap.Name)
+		}
+
+		data, err := parseClusterInventory([]byte(rawData))
+		if err != nil {
+			return nil, fmt.Errorf("ConfigMap %s/%s: %s", configMap.Namespace, configMap.Name, err)
+		}
+
+		err = mergo.Merge(&result, data, mergo.WithOverride)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// clusterInventoryFile reads the cluster inventory from a local file or, if a
+// backend is given, retrieves it with the matching loader (supporting
+// encrypted remote files)
+func clusterInventoryFile(source invconfig.ClusterInventorySource) (map[string]interface{}, error) {
+	var rawData []byte
+	var err error
+	switch {
+	case source.Backend != "":
+		var ldr loader.Loader
+		ldr, err = loader.New(source.Backend, source.Params)
+		if err != nil {
+			return nil, err
+		}
+		rawData, err = ldr.Load()
+	case source.Path != "":
+		rawData, err = ioutil.ReadFile(source.Path)
+	default:
+		return nil, fmt.Errorf("cluster-inventory file source requires either a path or a backend")
+	}
+	if err != nil {
+		if source.Optional && loader.IsNotFound(err) {
+			return map[string]interface{}{}, nil
+		}
+		return nil, fmt.Errorf("failed to load cluster-inventory file: %s", err)
+	}
+
+	return parseClusterInventory(rawData)
+}
+
+func parseClusterInventory(rawData []byte) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	err := yaml.Unmarshal(rawData, &data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse cluster-inventory as yaml/json: %s", err)
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
+
+// ClusterInventoryData returns the data stored in the cluster inventory ConfigMap of the
+// entry together with the resource version of the ConfigMap. Only the ConfigMap
+// referenced by the namespace / configmap settings is considered, additional sources are
+// ignored. If the ConfigMap does not exist, empty data and an empty resource version
+// are returned.
+func (e *Entry) ClusterInventoryData() (map[string]interface{}, string, error) {
+	namespace := e.ClusterInventoryConfig().Namespace
+	name := e.ClusterInventoryConfig().ConfigMap
+
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return nil, "", err
+	}
+
+	configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return map[string]interface{}{}, "", nil
+		}
+		return nil, "", fmt.Errorf("ConfigMap %s/%s: %s", namespace, name, err)
+	}
+
+	rawData, ok := configMap.Data[defaultClusterInventoryKey]
+	if !ok {
+		return map[string]interface{}{}, configMap.ResourceVersion, nil
+	}
+
+	data, err := parseClusterInventory([]byte(rawData))
+	if err != nil {
+		return nil, "", err
+	}
+	return data, configMap.ResourceVersion, nil
+}
+
+// UpdateClusterInventoryData writes the given data to the cluster inventory ConfigMap
+// of the entry. The resource version must be the one returned by ClusterInventoryData().
+// If the ConfigMap was modified (or created) in the meantime, the update fails.
+func (e *Entry) UpdateClusterInventoryData(data map[string]interface{}, resourceVersion string) error {
+	namespace := e.ClusterInventoryConfig().Namespace
+	name := e.ClusterInventoryConfig().ConfigMap
+
+	rawData, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return err
+	}
+	configMaps := clientset.CoreV1().ConfigMaps(namespace)
+
+	if resourceVersion == "" {
+		configMap := &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: namespace,
+			},
+			Data: map[string]string{
+				defaultClusterInventoryKey: string(rawData),
+			},
+		}
+		_, err = configMaps.Create(context.Background(), configMap, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("failed to create ConfigMap %s/%s: %s", namespace, name, err)
+		}
+		return nil
+	}
+
+	configMap, err := configMaps.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("ConfigMap %s/%s: %s", namespace, name, err)
+	}
+
+	// the api server also rejects updates with an outdated resource version,
+	// checking it here gives a consistent error message
+	if configMap.ResourceVersion != resourceVersion {
+		return fmt.Errorf("ConfigMap %s/%s was modified concurrently (resource version %s, expected %s)", namespace, name, configMap.ResourceVersion, resourceVersion)
+	}
+
+	if configMap.Data == nil {
+		configMap.Data = map[string]string{}
+	}
+	configMap.Data[defaultClusterInventoryKey] = string(rawData)
+
+	_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update ConfigMap %s/%s: %s", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bedag/kusible/pkg/inventory/config"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterInventorySources(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster-inventory",
+				Namespace: "kube-system",
+			},
+			Data: map[string]string{
+				"inventory": "{source: configmap, configmap: true}",
+				"other":     "{source: other-key}",
+			},
+		},
+		&v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster-secrets",
+				Namespace: "kube-system",
+			},
+			Data: map[string][]byte{
+				"secrets": []byte("{source: secret, secret: true}"),
+			},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "b-inventory",
+				Namespace: "inventory",
+				Labels:    map[string]string{"kusible": "inventory"},
+			},
+			Data: map[string]string{
+				"inventory": "{source: b, b: true}",
+			},
+		},
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "a-inventory",
+				Namespace: "inventory",
+				Labels:    map[string]string{"kusible": "inventory"},
+			},
+			Data: map[string]string{
+				"inventory": "{source: a, a: true}",
+			},
+		},
+	)
+
+	tests := map[string]struct {
+		sources     []config.ClusterInventorySource
+		expected    map[string]interface{}
+		errExpected bool
+	}{
+		"default": {
+			sources:  []config.ClusterInventorySource{},
+			expected: map[string]interface{}{"source": "configmap", "configmap": true},
+		},
+		"configmap key": {
+			sources: []config.ClusterInventorySource{
+				{Type: "configmap", Name: "cluster-inventory", Key: "other"},
+			},
+			expected: map[string]interface{}{"source": "other-key"},
+		},
+		"secret": {
+			sources: []config.ClusterInventorySource{
+				{Type: "secret", Name: "cluster-secrets", Key: "secrets"},
+			},
+			expected: map[string]interface{}{"source": "secret", "secret": true},
+		},
+		"selector": {
+			sources: []config.ClusterInventorySource{
+				{Type: "selector", Namespace: "inventory", Selector: "kusible=inventory"},
+			},
+			expected: map[string]interface{}{"source": "b", "a": true, "b": true},
+		},
+		"file": {
+			sources: []config.ClusterInventorySource{
+				{Type: "file", Path: "testdata/cluster_inventory.yaml"},
+			},
+			expected: map[string]interface{}{"source": "file", "file": map[string]interface{}{"key": "value"}},
+		},
+		"declared order": {
+			sources: []config.ClusterInventorySource{
+				{Type: "file", Path: "testdata/cluster_inventory.yaml"},
+				{Type: "configmap", Name: "cluster-inventory"},
+				{Type: "secret", Name: "cluster-secrets", Key: "secrets"},
+			},
+			expected: map[string]interface{}{
+				"source":    "secret",
+				"file":      map[string]interface{}{"key": "value"},
+				"configmap": true,
+				"secret":    true,
+			},
+		},
+		"missing": {
+			sources: []config.ClusterInventorySource{
+				{Type: "secret", Name: "missing"},
+			},
+			errExpected: true,
+		},
+		"missing optional": {
+			sources: []config.ClusterInventorySource{
+				{Type: "secret", Name: "missing", Optional: true},
+				{Type: "file", Path: "testdata/missing.yaml", Optional: true},
+			},
+			expected: map[string]interface{}{},
+		},
+		"unreadable optional": {
+			sources: []config.ClusterInventorySource{
+				{Type: "file", Path: "testdata", Optional: true},
+			},
+			errExpected: true,
+		},
+		"unknown type": {
+			sources: []config.ClusterInventorySource{
+				{Type: "unknown"},
+			},
+			errExpected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			entry := &Entry{
+				name:   "test",
+				groups: []string{"test"},
+				clusterInventoryConfig: &config.ClusterInventory{
+					Namespace: "kube-system",
+					ConfigMap: "cluster-inventory",
+					Sources:   tc.sources,
+				},
+				kubeconfig: &Kubeconfig{client: clientset},
+			}
+
+			result, err := entry.ClusterInventory()
+			assert.Equal(t, tc.errExpected, err != nil)
+			if tc.errExpected {
+				return
+			}
+			assert.DeepEqual(t, map[string]interface{}{"vars": tc.expected}, *result)
+		})
+	}
+}
+
+func TestClusterInventoryData(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            "cluster-inventory",
+				Namespace:       "kube-system",
+				ResourceVersion: "1",
+			},
+			Data: map[string]string{
+				"inventory": "{a: 1, b: {c: true}}",
+				"other":     "untouched",
+			},
+		},
+	)
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: &Kubeconfig{client: clientset},
+		clusterInventoryConfig: &config.ClusterInventory{
+			Namespace: "kube-system",
+			ConfigMap: "cluster-inventory",
+		},
+	}
+
+	data, resourceVersion, err := entry.ClusterInventoryData()
+	assert.NilError(t, err)
+	assert.Equal(t, "1", resourceVersion)
+	assert.DeepEqual(t, map[string]interface{}{"a": float64(1), "b": map[string]interface{}{"c": true}}, data)
+
+	err = entry.UpdateClusterInventoryData(map[string]interface{}{"a": 2}, "0")
+	assert.ErrorContains(t, err, "modified concurrently")
+
+	err = entry.UpdateClusterInventoryData(map[string]interface{}{"a": 2}, resourceVersion)
+	assert.NilError(t, err)
+
+	data, _, err = entry.ClusterInventoryData()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]interface{}{"a": float64(2)}, data)
+
+	configMap, err := clientset.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "cluster-inventory", metav1.GetOptions{})
+	assert.NilError(t, err)
+	assert.Equal(t, "untouched", configMap.Data["other"])
+}
+
+func TestClusterInventoryDataCreate(t *testing.T) {
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: &Kubeconfig{client: fake.NewSimpleClientset()},
+		clusterInventoryConfig: &config.ClusterInventory{
+			Namespace: "kube-system",
+			ConfigMap: "cluster-inventory",
+		},
+	}
+
+	data, resourceVersion, err := entry.ClusterInventoryData()
+	assert.NilError(t, err)
+	assert.Equal(t, "", resourceVersion)
+	assert.DeepEqual(t, map[string]interface{}{}, data)
+
+	err = entry.UpdateClusterInventoryData(map[string]interface{}{"a": "b"}, "")
+	assert.NilError(t, err)
+
+	data, _, err = entry.ClusterInventoryData()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]interface{}{"a": "b"}, data)
+
+	// creating it again must fail as the ConfigMap now exists
+	err = entry.UpdateClusterInventoryData(map[string]interface{}{"a": "c"}, "")
+	assert.Assert(t, err != nil)
+}
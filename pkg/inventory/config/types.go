@@ -18,6 +18,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/imdario/mergo"
 	"github.com/mitchellh/mapstructure"
@@ -40,6 +41,8 @@ type Entry struct {
 	// Each entry is always part of the "all" group and a group
 	// with the name of the entry.
 	Groups []string `json:"groups"`
+	// Labels are arbitrary key / value pairs describing the entry
+	Labels map[string]string `json:"labels,omitempty"`
 	// Location of the "Cluster Inventory"
 	ClusterInventory ClusterInventory `json:"cluster_inventory"`
 	// Kubeconfig holds the kubeconfig loader configuration
@@ -51,6 +54,37 @@ type Entry struct {
 type ClusterInventory struct {
 	Namespace string `json:"namespace"`
 	ConfigMap string `json:"configmap"`
+	// Sources is an ordered list of locations the cluster inventory
+	// is assembled from. If empty, the ConfigMap in the Namespace
+	// given above is used.
+	Sources []ClusterInventorySource `json:"sources,omitempty"`
+}
+
+// ClusterInventorySource describes a single location holding (a part of)
+// the cluster inventory. The data of all sources is merged in the
+// order of the sources.
+type ClusterInventorySource struct {
+	// Type of the source: configmap, secret, selector or file
+	Type string `json:"type"`
+	// Namespace of the configmap / secret / selected configmaps.
+	// Defaults to the namespace of the cluster inventory.
+	Namespace string `json:"namespace"`
+	// Name of the configmap or secret
+	Name string `json:"name"`
+	// Key in the data of the configmap(s) or secret holding the
+	// cluster inventory as yaml / json. Defaults to "inventory".
+	Key string `json:"key"`
+	// Selector is a label selector used to select configmaps. All
+	// selected configmaps are merged in the order of their names.
+	Selector string `json:"selector"`
+	// Path of a local file holding the cluster inventory
+	Path string `json:"path"`
+	// Backend and Params define a kubeconfig loader backend used to
+	// retrieve a remote file holding the cluster inventory
+	Backend string `json:"backend"`
+	Params  Params `json:"params"`
+	// Optional sources are skipped if they do not exist
+	Optional bool `json:"optional"`
 }
 
 // Kubeconfig holds information on how / where to retrieve / generate
@@ -110,11 +144,28 @@ func NewConfigFromMap(data *map[string]interface{}) (*Config, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		for _, source := range entry.ClusterInventory.Sources {
+			err := source.validate()
+			if err != nil {
+				return nil, fmt.Errorf("entry '%s': %s", entry.Name, err)
+			}
+		}
 		config.Inventory[index] = entry
 	}
 	return &config, err
 }
 
+// validate returns an error if a setting required by the type
+// of the source is missing
+func (s *ClusterInventorySource) validate() error {
+	// an empty label selector would select all configmaps in the namespace
+	if strings.ToLower(s.Type) == "selector" && s.Selector == "" {
+		return fmt.Errorf("cluster-inventory selector source requires a selector")
+	}
+	return nil
+}
+
 // NewConfig returns an empty inventory config
 func NewConfig() *Config {
 	return &Config{
@@ -98,3 +98,21 @@ inventory:
 	assert.Assert(t, config.Inventory[0].Kubeconfig.Params != nil)
 	assert.Equal(t, "testentry/kubeconfig/kubeconfig.enc.7z", config.Inventory[0].Kubeconfig.Params["path"])
 }
+
+func TestEmptySelector(t *testing.T) {
+	data := []byte(`---
+inventory:
+  - name: "testentry"
+    cluster_inventory:
+      sources:
+        - type: selector
+          namespace: inventory
+`)
+
+	var configMap map[string]interface{}
+	err := yaml.Unmarshal(data, &configMap)
+	assert.NilError(t, err)
+
+	_, err = NewConfigFromMap(&configMap)
+	assert.ErrorContains(t, err, "requires a selector")
+}
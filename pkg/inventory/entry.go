@@ -16,15 +16,12 @@ limitations under the License.
 package inventory
 
 import (
-	"context"
 	"fmt"
 	"regexp"
 
 	"github.com/bedag/kusible/pkg/groups"
 	invconfig "github.com/bedag/kusible/pkg/inventory/config"
 	"github.com/imdario/mergo"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"sigs.k8s.io/yaml"
 )
 
 func NewEntryFromConfig(config *invconfig.Entry) (*Entry, error) {
@@ -35,6 +32,7 @@ func NewEntryFromConfig(config *invconfig.Entry) (*Entry, error) {
 
 	entry := &Entry{
 		name:                   config.Name,
+		labels:                 config.Labels,
 		clusterInventoryConfig: &config.ClusterInventory,
 		kubeconfig:             kubeconfig,
 	}
@@ -42,6 +40,7 @@ func NewEntryFromConfig(config *invconfig.Entry) (*Entry, error) {
 	// set "entry" level defaults here
 	entry.groups = append([]string{"all"}, config.Groups...)
 	entry.groups = append(entry.groups, config.Name)
+	entry.hostGroup = true
 
 	return entry, nil
 }
@@ -113,6 +112,21 @@ func (e *Entry) Groups() []string {
 	return e.groups
 }
 
+// ConfiguredGroups returns the groups of the entry without the implicit
+// group named like the entry. Groups named like the entry that are part
+// of the inventory config of the entry are kept.
+func (e *Entry) ConfiguredGroups() []string {
+	if !e.hostGroup || len(e.groups) < 1 {
+		return e.groups
+	}
+	return append([]string{}, e.groups[:len(e.groups)-1]...)
+}
+
+// Labels returns the labels of the entry
+func (e *Entry) Labels() map[string]string {
+	return e.labels
+}
+
 func (e *Entry) Name() string {
 	return e.name
 }
@@ -121,29 +135,47 @@ func (e *Entry) ClusterInventoryConfig() *invconfig.ClusterInventory {
 	return e.clusterInventoryConfig
 }
 
+// ClusterInventory retrieves the cluster inventory of the entry from all configured
+// sources (or the snapshot directory, if set) and returns it in the "vars" key of
+// the resulting map
 func (e *Entry) ClusterInventory() (*map[string]interface{}, error) {
-	clientset, err := e.kubeconfig.Client()
-	if err != nil {
-		return nil, err
+	if e.snapshotDir != "" {
+		snapshot, err := ReadSnapshot(e.snapshotDir, e.name)
+		if err != nil {
+			return nil, err
+		}
+		result := map[string]interface{}{
+			"vars": snapshot.ClusterInventory,
+		}
+		return &result, nil
 	}
 
-	configMap, err := clientset.CoreV1().ConfigMaps(e.ClusterInventoryConfig().Namespace).Get(context.Background(), e.ClusterInventoryConfig().ConfigMap, metav1.GetOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("ConfigMap %s/%s: %s", e.ClusterInventoryConfig().Namespace, e.ClusterInventoryConfig().ConfigMap, err)
+	sources := e.ClusterInventoryConfig().Sources
+	if len(sources) <= 0 {
+		sources = []invconfig.ClusterInventorySource{
+			{
+				Type:      ClusterInventorySourceConfigMap,
+				Namespace: e.ClusterInventoryConfig().Namespace,
+				Name:      e.ClusterInventoryConfig().ConfigMap,
+			},
+		}
 	}
 
-	rawData, ok := configMap.Data["inventory"]
-	if !ok {
-		return nil, fmt.Errorf("wrong cluster-inventory format: expecting 'inventory' key in configmap data")
-	}
-	var data map[string]interface{}
-	err = yaml.Unmarshal([]byte(rawData), &data)
-	if err != nil {
-		return nil, fmt.Errorf("cannot parse cluster-inventory as yaml/json: %s", err)
+	data := map[string]interface{}{}
+	for _, source := range sources {
+		sourceData, err := e.clusterInventorySource(source)
+		if err != nil {
+			return nil, err
+		}
+
+		err = mergo.Merge(&data, sourceData, mergo.WithOverride)
+		if err != nil {
+			return nil, fmt.Errorf("failed to merge cluster-inventory sources: %s", err)
+		}
 	}
+
 	result := map[string]interface{}{
 		"vars": data,
 	}
-
 	return &result, nil
 }
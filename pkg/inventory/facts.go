@@ -0,0 +1,302 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// annotation marking the default storage class of a cluster
+const defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+
+// SetFactsCache configures the facts cache used by all entries
+// of the inventory
+func (i *Inventory) SetFactsCache(cache *FactsCache) {
+	for _, entry := range i.entries {
+		entry.SetFactsCache(cache)
+	}
+}
+
+// SetFactsCache configures the facts cache of the entry
+func (e *Entry) SetFactsCache(cache *FactsCache) {
+	e.factsCache = cache
+}
+
+// Facts returns information about the cluster of the entry, similar
+// to the facts gathered by ansible. Facts the credentials of the entry
+// are not allowed to query are left out and listed in the "missing"
+// fact instead. Gathered facts are written to the facts cache (if
+// configured). If the facts cache is in offline mode, the facts are
+// only read from the cache.
+func (e *Entry) Facts() (*map[string]interface{}, error) {
+	if e.factsCache != nil && e.factsCache.Offline {
+		return e.factsCache.read(e.name)
+	}
+
+	clientset, err := e.kubeconfig.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	facts, err := gatherFacts(clientset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to gather facts: %s", err)
+	}
+
+	if e.factsCache != nil && e.factsCache.Dir != "" {
+		err = e.factsCache.write(e.name, facts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &facts, nil
+}
+
+func gatherFacts(clientset kubernetes.Interface) (map[string]interface{}, error) {
+	ctx := context.Background()
+	discovery := clientset.Discovery()
+
+	version, err := discovery.ServerVersion()
+	if err != nil {
+		return nil, fmt.Errorf("server version: %s", err)
+	}
+
+	groupVersions, err := serverAPIVersions(clientset)
+	if err != nil {
+		return nil, err
+	}
+	apiVersions := []interface{}{}
+	hasCRDv1 := false
+	for _, groupVersion := range groupVersions {
+		apiVersions = append(apiVersions, groupVersion)
+		if groupVersion == "apiextensions.k8s.io/v1" {
+			hasCRDv1 = true
+		}
+	}
+
+	facts := map[string]interface{}{
+		"kubernetes": map[string]interface{}{
+			"version":  version.GitVersion,
+			"major":    version.Major,
+			"minor":    version.Minor,
+			"platform": version.Platform,
+		},
+		"api_versions": apiVersions,
+	}
+	// facts the credentials of the entry are not allowed to query
+	missing := []interface{}{}
+
+	nodeList, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		missing = append(missing, "nodes")
+	case err != nil:
+		return nil, fmt.Errorf("nodes: %s", err)
+	default:
+		nodeLabels := map[string]interface{}{}
+		for _, node := range nodeList.Items {
+			nodeLabels[node.Name] = stringMap(node.Labels)
+		}
+		facts["nodes"] = map[string]interface{}{
+			"count":  len(nodeList.Items),
+			"labels": nodeLabels,
+		}
+	}
+
+	storageClassList, err := clientset.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		missing = append(missing, "storage_classes")
+	case err != nil:
+		return nil, fmt.Errorf("storage classes: %s", err)
+	default:
+		storageClasses := []interface{}{}
+		defaultStorageClass := ""
+		for _, sc := range storageClassList.Items {
+			isDefault := sc.Annotations[defaultStorageClassAnnotation] == "true"
+			if isDefault {
+				defaultStorageClass = sc.Name
+			}
+			storageClasses = append(storageClasses, map[string]interface{}{
+				"name":        sc.Name,
+				"provisioner": sc.Provisioner,
+				"default":     isDefault,
+			})
+		}
+		facts["storage_classes"] = map[string]interface{}{
+			"default": defaultStorageClass,
+			"items":   storageClasses,
+		}
+	}
+
+	namespace, err := clientset.CoreV1().Namespaces().Get(ctx, metav1.NamespaceDefault, metav1.GetOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		missing = append(missing, "default_namespace")
+	case err != nil:
+		return nil, fmt.Errorf("default namespace: %s", err)
+	default:
+		facts["default_namespace"] = map[string]interface{}{
+			"annotations": stringMap(namespace.Annotations),
+		}
+	}
+
+	crds, err := customResourceDefinitions(clientset, hasCRDv1)
+	switch {
+	case apierrors.IsForbidden(err):
+		missing = append(missing, "crds")
+	case err != nil:
+		return nil, fmt.Errorf("custom resource definitions: %s", err)
+	default:
+		facts["crds"] = crds
+	}
+
+	facts["missing"] = missing
+
+	// normalize the facts to the same data types as data
+	// loaded from yaml files
+	return normalize(facts)
+}
+
+// serverAPIVersions returns the sorted list of all group versions
+// served by the cluster
+func serverAPIVersions(clientset kubernetes.Interface) ([]string, error) {
+	groupList, err := clientset.Discovery().ServerGroups()
+	if err != nil {
+		return nil, fmt.Errorf("api groups: %s", err)
+	}
+
+	result := []string{}
+	for _, group := range groupList.Groups {
+		for _, v := range group.Versions {
+			result = append(result, v.GroupVersion)
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// customResourceDefinitions returns the names of all CRDs installed in the cluster.
+// The typed kubernetes client does not cover CRDs so the raw REST client
+// of the discovery client is used.
+func customResourceDefinitions(clientset kubernetes.Interface, v1 bool) ([]interface{}, error) {
+	result := []interface{}{}
+
+	restClient := clientset.Discovery().RESTClient()
+	if restClient == nil {
+		return result, nil
+	}
+
+	path := "/apis/apiextensions.k8s.io/v1beta1/customresourcedefinitions"
+	if v1 {
+		path = "/apis/apiextensions.k8s.io/v1/customresourcedefinitions"
+	}
+
+	raw, err := restClient.Get().AbsPath(path).DoRaw(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	var list metav1.PartialObjectMetadataList
+	err = json.Unmarshal(raw, &list)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, item := range list.Items {
+		names = append(names, item.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result = append(result, name)
+	}
+	return result, nil
+}
+
+func stringMap(m map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = v
+	}
+	return result
+}
+
+func normalize(data map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := yaml.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var result map[string]interface{}
+	err = yaml.Unmarshal(raw, &result)
+	return result, err
+}
+
+func (c *FactsCache) path(entry string) string {
+	return filepath.Join(c.Dir, fmt.Sprintf("%s.yaml", entry))
+}
+
+func (c *FactsCache) read(entry string) (*map[string]interface{}, error) {
+	if c.Dir == "" {
+		return nil, fmt.Errorf("offline facts require a facts cache directory")
+	}
+
+	raw, err := ioutil.ReadFile(c.path(entry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached facts: %s", err)
+	}
+
+	var facts map[string]interface{}
+	err = yaml.Unmarshal(raw, &facts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse cached facts as yaml/json: %s", err)
+	}
+	if facts == nil {
+		facts = map[string]interface{}{}
+	}
+	return &facts, nil
+}
+
+func (c *FactsCache) write(entry string, facts map[string]interface{}) error {
+	raw, err := yaml.Marshal(facts)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(c.Dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create facts cache directory: %s", err)
+	}
+
+	err = ioutil.WriteFile(c.path(entry), raw, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write cached facts: %s", err)
+	}
+	return nil
+}
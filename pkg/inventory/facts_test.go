@@ -0,0 +1,155 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newFactsTestEntry() *Entry {
+	clientset := fake.NewSimpleClientset(
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-01",
+				Labels: map[string]string{"zone": "a"},
+			},
+		},
+		&v1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "node-02",
+				Labels: map[string]string{"zone": "b"},
+			},
+		},
+		&storagev1.StorageClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "standard",
+				Annotations: map[string]string{defaultStorageClassAnnotation: "true"},
+			},
+			Provisioner: "kubernetes.io/no-provisioner",
+		},
+		&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "default",
+				Annotations: map[string]string{"owner": "platform"},
+			},
+		},
+	)
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.FakedServerVersion = &version.Info{
+		GitVersion: "v1.20.1",
+		Major:      "1",
+		Minor:      "20",
+	}
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1"},
+		{GroupVersion: "apps/v1"},
+	}
+
+	return &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: &Kubeconfig{client: clientset},
+	}
+}
+
+func TestFacts(t *testing.T) {
+	entry := newFactsTestEntry()
+
+	facts, err := entry.Facts()
+	assert.NilError(t, err)
+
+	kubernetes := (*facts)["kubernetes"].(map[string]interface{})
+	assert.Equal(t, "v1.20.1", kubernetes["version"])
+	assert.Equal(t, "20", kubernetes["minor"])
+
+	nodes := (*facts)["nodes"].(map[string]interface{})
+	assert.Equal(t, float64(2), nodes["count"])
+	labels := nodes["labels"].(map[string]interface{})
+	assert.DeepEqual(t, map[string]interface{}{"zone": "a"}, labels["node-01"])
+
+	assert.DeepEqual(t, []interface{}{"apps/v1", "v1"}, (*facts)["api_versions"])
+	assert.DeepEqual(t, []interface{}{}, (*facts)["crds"])
+
+	storageClasses := (*facts)["storage_classes"].(map[string]interface{})
+	assert.Equal(t, "standard", storageClasses["default"])
+
+	namespace := (*facts)["default_namespace"].(map[string]interface{})
+	assert.DeepEqual(t, map[string]interface{}{"owner": "platform"}, namespace["annotations"])
+	assert.DeepEqual(t, []interface{}{}, (*facts)["missing"])
+}
+
+func TestFactsCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kusible-facts")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	entry := newFactsTestEntry()
+	entry.SetFactsCache(&FactsCache{Dir: dir})
+	gathered, err := entry.Facts()
+	assert.NilError(t, err)
+
+	offlineEntry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: &Kubeconfig{},
+	}
+	offlineEntry.SetFactsCache(&FactsCache{Dir: dir, Offline: true})
+	cached, err := offlineEntry.Facts()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *gathered, *cached)
+
+	missingEntry := &Entry{
+		name:       "missing",
+		groups:     []string{"missing"},
+		kubeconfig: &Kubeconfig{},
+	}
+	missingEntry.SetFactsCache(&FactsCache{Dir: dir, Offline: true})
+	_, err = missingEntry.Facts()
+	assert.Assert(t, err != nil)
+}
+
+func TestFactsForbidden(t *testing.T) {
+	entry := newFactsTestEntry()
+	clientset := entry.kubeconfig.client.(*fake.Clientset)
+	clientset.PrependReactor("list", "nodes", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(v1.Resource("nodes"), "", fmt.Errorf("restricted"))
+	})
+
+	facts, err := entry.Facts()
+	assert.NilError(t, err)
+
+	_, ok := (*facts)["nodes"]
+	assert.Assert(t, !ok)
+	assert.DeepEqual(t, []interface{}{"nodes"}, (*facts)["missing"])
+
+	storageClasses := (*facts)["storage_classes"].(map[string]interface{})
+	assert.Equal(t, "standard", storageClasses["default"])
+}
@@ -28,7 +28,7 @@ import (
 
 func NewInventory(path string, ejson ejson.Settings, skipKubeconfig bool, defaulClusterInventoryConfig invconfig.ClusterInventory) (*Inventory, error) {
 	// load the raw inventory yaml data
-	raw, err := values.New(path, []string{}, false, ejson)
+	raw, err := values.New(path, []string{}, values.Options{Ejson: ejson})
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +31,7 @@ import (
 func basicInventoryTest(path string, filter string, limits []string, skip bool, clusterInvConfig config.ClusterInventory, expected []string) (*Inventory, error) {
 	ejsonSettings := ejson.Settings{
 		PrivKey:     "",
-		KeyDir:      "",
+		KeyDirs:     []string{},
 		SkipDecrypt: false,
 	}
 
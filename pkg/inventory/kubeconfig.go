@@ -86,6 +86,20 @@ func (k *Kubeconfig) Config() (clientcmd.ClientConfig, error) {
 	return k.config, nil
 }
 
+// Server returns the server URL of the current context of the kubeconfig.
+// The kubeconfig is not loaded by this, if it was not loaded yet, the
+// result is empty.
+func (k *Kubeconfig) Server() string {
+	if k.config == nil {
+		return ""
+	}
+	clientConfig, err := k.config.ClientConfig()
+	if err != nil {
+		return ""
+	}
+	return clientConfig.Host
+}
+
 func (k *Kubeconfig) SetClient(clientset kubernetes.Interface) {
 	k.client = clientset
 }
@@ -0,0 +1,137 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Ping checks if the cluster of the entry can be reached by calling
+// the discovery API of the cluster. Errors are not returned but
+// recorded in the result, as an unreachable cluster is a valid
+// outcome of a ping. A timeout > 0 limits the duration of the
+// requests to the cluster.
+func (e *Entry) Ping(timeout time.Duration) *PingResult {
+	result := &PingResult{}
+
+	// a broken client certificate does not prevent the ping, as the
+	// cluster may still be reachable
+	expiry, err := e.kubeconfig.ClientCertificateExpiry()
+	if err != nil {
+		result.CertificateError = err.Error()
+	}
+	result.CertificateExpiry = expiry
+
+	clientset, err := e.kubeconfig.clientWithTimeout(timeout)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	start := time.Now()
+	version, err := clientset.Discovery().ServerVersion()
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Error = err.Error()
+		result.AuthError = apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err)
+		// the server answered, so it is reachable, we are just not allowed
+		// to talk to it
+		result.Reachable = result.AuthError
+		return result
+	}
+
+	result.Reachable = true
+	result.ServerVersion = version.GitVersion
+	return result
+}
+
+// clientWithTimeout returns a new clientset for the current kubeconfig
+// using the given request timeout. An already existing client is reused.
+func (k *Kubeconfig) clientWithTimeout(timeout time.Duration) (kubernetes.Interface, error) {
+	if k.client != nil || timeout <= 0 {
+		return k.Client()
+	}
+
+	config, err := k.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	restConfig, err := config.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	restConfig.Timeout = timeout
+
+	return kubernetes.NewForConfig(restConfig)
+}
+
+// ClientCertificateExpiry returns the expiry date of the client certificate
+// used by the current context of the kubeconfig. If the kubeconfig does
+// not use a client certificate, nil is returned.
+func (k *Kubeconfig) ClientCertificateExpiry() (*time.Time, error) {
+	clientConfig, err := k.Config()
+	if err != nil {
+		return nil, err
+	}
+
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	context, ok := rawConfig.Contexts[rawConfig.CurrentContext]
+	if !ok {
+		return nil, nil
+	}
+
+	authInfo, ok := rawConfig.AuthInfos[context.AuthInfo]
+	if !ok {
+		return nil, nil
+	}
+
+	certData := authInfo.ClientCertificateData
+	if len(certData) <= 0 && authInfo.ClientCertificate != "" {
+		certData, err = ioutil.ReadFile(authInfo.ClientCertificate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(certData) <= 0 {
+		return nil, nil
+	}
+
+	block, _ := pem.Decode(certData)
+	if block == nil {
+		return nil, fmt.Errorf("failed to decode client certificate of user '%s'", context.AuthInfo)
+	}
+
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse client certificate of user '%s': %s", context.AuthInfo, err)
+	}
+
+	return &cert.NotAfter, nil
+}
@@ -0,0 +1,203 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bedag/kusible/pkg/loader"
+	"gotest.tools/assert"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+func TestPing(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.20.1",
+	}
+
+	config := clientcmdapi.NewConfig()
+	k := &Kubeconfig{
+		config: clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}),
+		client: clientset,
+	}
+
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: k,
+	}
+
+	result := entry.Ping(time.Second)
+	assert.Equal(t, "", result.Error)
+	assert.Assert(t, result.Reachable)
+	assert.Assert(t, !result.AuthError)
+	assert.Equal(t, "v1.20.1", result.ServerVersion)
+	assert.Assert(t, result.CertificateExpiry == nil)
+	assert.Equal(t, "", result.CertificateError)
+}
+
+func TestPingCertificateError(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &version.Info{
+		GitVersion: "v1.20.1",
+	}
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://1.2.3.4"}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{ClientCertificateData: []byte("invalid")}
+	config.Contexts["cluster-user"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	config.CurrentContext = "cluster-user"
+	k := &Kubeconfig{
+		config: clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}),
+		client: clientset,
+	}
+
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: k,
+	}
+
+	// the certificate error is reported alongside the ping result
+	result := entry.Ping(time.Second)
+	assert.Assert(t, result.CertificateError != "")
+	assert.Assert(t, result.Reachable)
+	assert.Equal(t, "v1.20.1", result.ServerVersion)
+}
+
+func TestPingTimeout(t *testing.T) {
+	// a server that accepts connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "http://" + listener.Addr().String()}
+	config.AuthInfos["user"] = &clientcmdapi.AuthInfo{}
+	config.Contexts["cluster-user"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+	config.CurrentContext = "cluster-user"
+	k := &Kubeconfig{
+		config: clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}),
+	}
+
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: k,
+	}
+
+	start := time.Now()
+	result := entry.Ping(200 * time.Millisecond)
+	assert.Assert(t, !result.Reachable)
+	assert.Assert(t, result.Error != "")
+	assert.Assert(t, time.Since(start) < 5*time.Second)
+}
+
+func TestPingUnreachable(t *testing.T) {
+	k, err := NewKubeconfigFromLoader(loader.NewFileBackend("testdata/does-not-exist", ""))
+	assert.NilError(t, err)
+
+	entry := &Entry{
+		name:       "test",
+		groups:     []string{"test"},
+		kubeconfig: k,
+	}
+
+	result := entry.Ping(time.Second)
+	assert.Assert(t, !result.Reachable)
+	assert.Assert(t, result.Error != "")
+}
+
+func TestClientCertificateExpiry(t *testing.T) {
+	notAfter := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "developer"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	certData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	tests := map[string]struct {
+		authInfo *clientcmdapi.AuthInfo
+		expiry   *time.Time
+		err      bool
+	}{
+		"certificate": {
+			authInfo: &clientcmdapi.AuthInfo{ClientCertificateData: certData},
+			expiry:   &notAfter,
+		},
+		"token": {
+			authInfo: &clientcmdapi.AuthInfo{Token: "secret"},
+			expiry:   nil,
+		},
+		"invalid certificate": {
+			authInfo: &clientcmdapi.AuthInfo{ClientCertificateData: []byte("invalid")},
+			err:      true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := clientcmdapi.NewConfig()
+			config.Clusters["cluster"] = &clientcmdapi.Cluster{Server: "https://1.2.3.4"}
+			config.AuthInfos["user"] = tc.authInfo
+			config.Contexts["cluster-user"] = &clientcmdapi.Context{Cluster: "cluster", AuthInfo: "user"}
+			config.CurrentContext = "cluster-user"
+
+			k := &Kubeconfig{
+				config: clientcmd.NewDefaultClientConfig(*config, &clientcmd.ConfigOverrides{}),
+			}
+
+			expiry, err := k.ClientCertificateExpiry()
+			assert.Equal(t, tc.err, err != nil)
+			if tc.expiry == nil {
+				assert.Assert(t, expiry == nil)
+				return
+			}
+			assert.Assert(t, expiry != nil)
+			assert.Assert(t, tc.expiry.Equal(*expiry))
+		})
+	}
+}
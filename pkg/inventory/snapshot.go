@@ -0,0 +1,118 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+// SetSnapshotDir makes all entries of the inventory read their
+// cluster inventory from the given snapshot directory
+func (i *Inventory) SetSnapshotDir(dir string) {
+	for _, entry := range i.entries {
+		entry.SetSnapshotDir(dir)
+	}
+}
+
+// SetSnapshotDir makes the entry read its cluster inventory from
+// the given snapshot directory instead of the cluster
+func (e *Entry) SetSnapshotDir(dir string) {
+	e.snapshotDir = dir
+}
+
+// Snapshot records the cluster inventory and (optionally) the api versions
+// of the cluster of the entry
+func (e *Entry) Snapshot(apiVersions bool) (*Snapshot, error) {
+	clusterInventory, err := e.ClusterInventory()
+	if err != nil {
+		return nil, err
+	}
+
+	vars, ok := (*clusterInventory)["vars"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected cluster-inventory format of entry '%s'", e.name)
+	}
+
+	snapshot := &Snapshot{
+		ClusterInventory: vars,
+	}
+
+	if apiVersions {
+		clientset, err := e.kubeconfig.Client()
+		if err != nil {
+			return nil, err
+		}
+		snapshot.APIVersions, err = serverAPIVersions(clientset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return snapshot, nil
+}
+
+func snapshotPath(dir string, entry string) string {
+	return filepath.Join(dir, fmt.Sprintf("%s.yaml", entry))
+}
+
+// ReadSnapshot reads the snapshot of the given entry from the snapshot directory
+func ReadSnapshot(dir string, entry string) (*Snapshot, error) {
+	raw, err := ioutil.ReadFile(snapshotPath(dir, entry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %s", err)
+	}
+
+	var snapshot Snapshot
+	err = yaml.Unmarshal(raw, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse snapshot as yaml/json: %s", err)
+	}
+	if snapshot.ClusterInventory == nil {
+		snapshot.ClusterInventory = map[string]interface{}{}
+	}
+	return &snapshot, nil
+}
+
+// WriteSnapshot writes the snapshot of the given entry to the snapshot directory
+func WriteSnapshot(dir string, entry string, snapshot *Snapshot) error {
+	raw, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %s", err)
+	}
+
+	// the cluster inventory might contain sensitive data, the mode of
+	// existing snapshots is not changed by WriteFile
+	path := snapshotPath(dir, entry)
+	err = ioutil.WriteFile(path, raw, 0600)
+	if err == nil {
+		err = os.Chmod(path, 0600)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot: %s", err)
+	}
+	return nil
+}
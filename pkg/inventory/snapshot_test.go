@@ -0,0 +1,100 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package inventory
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/bedag/kusible/pkg/inventory/config"
+	"gotest.tools/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kusible-snapshot")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	clientset := fake.NewSimpleClientset(
+		&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "cluster-inventory",
+				Namespace: "kube-system",
+			},
+			Data: map[string]string{
+				"inventory": "{k8s: {cluster_name: test}}",
+			},
+		},
+	)
+	discovery := clientset.Discovery().(*fakediscovery.FakeDiscovery)
+	discovery.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "v1"},
+		{GroupVersion: "apps/v1"},
+	}
+
+	clusterInventoryConfig := &config.ClusterInventory{
+		Namespace: "kube-system",
+		ConfigMap: "cluster-inventory",
+	}
+	entry := &Entry{
+		name:                   "test",
+		groups:                 []string{"test"},
+		kubeconfig:             &Kubeconfig{client: clientset},
+		clusterInventoryConfig: clusterInventoryConfig,
+	}
+
+	snapshot, err := entry.Snapshot(true)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"apps/v1", "v1"}, snapshot.APIVersions)
+
+	err = WriteSnapshot(dir, entry.Name(), snapshot)
+	assert.NilError(t, err)
+	info, err := os.Stat(snapshotPath(dir, entry.Name()))
+	assert.NilError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	// the kubeconfig of the offline entry has no client and no loader,
+	// retrieving the cluster inventory from the cluster would fail
+	offlineEntry := &Entry{
+		name:                   "test",
+		groups:                 []string{"test"},
+		kubeconfig:             &Kubeconfig{},
+		clusterInventoryConfig: clusterInventoryConfig,
+	}
+	offlineEntry.SetSnapshotDir(dir)
+
+	expected, err := entry.ClusterInventory()
+	assert.NilError(t, err)
+	result, err := offlineEntry.ClusterInventory()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, *expected, *result)
+
+	missingEntry := &Entry{
+		name:                   "missing",
+		groups:                 []string{"missing"},
+		kubeconfig:             &Kubeconfig{},
+		clusterInventoryConfig: clusterInventoryConfig,
+	}
+	missingEntry.SetSnapshotDir(dir)
+	_, err = missingEntry.ClusterInventory()
+	assert.Assert(t, err != nil)
+}
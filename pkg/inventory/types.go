@@ -17,6 +17,8 @@ limitations under the License.
 package inventory
 
 import (
+	"time"
+
 	"github.com/bedag/kusible/pkg/inventory/config"
 	"github.com/bedag/kusible/pkg/loader"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
@@ -32,8 +34,12 @@ type Inventory struct {
 type Entry struct {
 	name                   string
 	groups                 []string
+	hostGroup              bool // the last group is the implicit group named like the entry
+	labels                 map[string]string
 	clusterInventoryConfig *config.ClusterInventory
 	kubeconfig             *Kubeconfig
+	factsCache             *FactsCache
+	snapshotDir            string
 }
 
 type Kubeconfig struct {
@@ -41,3 +47,34 @@ type Kubeconfig struct {
 	config clientcmd.ClientConfig
 	client kubernetes.Interface // *kubernetes.Clientset
 }
+
+// FactsCache controls if and where gathered cluster facts
+// are cached
+type FactsCache struct {
+	// Dir is the directory holding one facts file per entry
+	Dir string
+	// Offline prevents gathering facts from the cluster, facts
+	// are only read from the cache
+	Offline bool
+}
+
+// Snapshot holds the cluster data of an inventory entry recorded
+// for offline rendering
+type Snapshot struct {
+	ClusterInventory map[string]interface{} `json:"cluster_inventory"`
+	APIVersions      []string               `json:"api_versions,omitempty"`
+}
+
+// PingResult holds the outcome of a connectivity check against
+// the cluster of an inventory entry
+type PingResult struct {
+	Reachable         bool
+	AuthError         bool
+	ServerVersion     string
+	Latency           time.Duration
+	CertificateExpiry *time.Time
+	// CertificateError is set if the expiry of the client
+	// certificate could not be determined
+	CertificateError string
+	Error            string
+}
@@ -0,0 +1,105 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package loader
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+func NewHTTPBackendFromConfig(config *HTTPConfig) *HTTPBackend {
+	return &HTTPBackend{
+		config: config,
+		Client: http.DefaultClient,
+	}
+}
+
+func NewHTTPBackendFromParams(params map[string]interface{}) (*HTTPBackend, error) {
+	config := HTTPConfig{
+		DecryptKey: os.Getenv("EJSON_PRIVKEY"),
+	}
+
+	err := decode(params, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewHTTPBackendFromConfig(&config), nil
+}
+
+func (b *HTTPBackend) Load() ([]byte, error) {
+	data, err := b.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return decodeKubeconfig(data, b.config.DecryptKey, b.config.Sanitize().(*HTTPConfig).URL)
+}
+
+// Fetch downloads the configured url without any further processing
+func (b *HTTPBackend) Fetch() ([]byte, error) {
+	if b.Client == nil {
+		return nil, fmt.Errorf("no http client configured")
+	}
+
+	if b.config.URL == "" {
+		return nil, fmt.Errorf("no url set for http backend")
+	}
+
+	resp, err := b.Client.Get(b.config.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("failed to download %s: %s", b.config.Sanitize().(*HTTPConfig).URL, resp.Status)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, &NotFoundError{Err: err}
+		}
+		return nil, err
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (b *HTTPBackend) Type() string {
+	return "http"
+}
+
+func (b *HTTPBackend) Config() BackendConfig {
+	return b.config
+}
+
+// Sanitize hashes the decrypt key and removes the password
+// from the url
+func (c *HTTPConfig) Sanitize() BackendConfig {
+	result := &HTTPConfig{
+		URL:        c.URL,
+		DecryptKey: fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(c.DecryptKey))),
+	}
+	if u, err := url.Parse(c.URL); err == nil {
+		result.URL = u.Redacted()
+	}
+	return result
+}
+
+func (c *HTTPConfig) Yaml(unsafe bool) ([]byte, error) {
+	return safeYaml(c, unsafe)
+}
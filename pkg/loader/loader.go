@@ -30,6 +30,8 @@ func New(backend string, params map[string]interface{}) (Loader, error) {
 		return NewS3BackendFromParams(params)
 	case "file":
 		return NewFileBackendFromParams(params)
+	case "http":
+		return NewHTTPBackendFromParams(params)
 	default:
 		return nil, fmt.Errorf("unknown kubeconfig backend: %s", backend)
 	}
@@ -29,6 +29,7 @@ func TestLoader(t *testing.T) {
 	}{
 		"file":    {backend: "file", errExpected: false},
 		"s3":      {backend: "s3", errExpected: false},
+		"http":    {backend: "http", errExpected: false},
 		"unknown": {backend: "unknown", errExpected: true},
 		"empty":   {backend: "", errExpected: true},
 	}
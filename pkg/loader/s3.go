@@ -17,18 +17,16 @@ limitations under the License.
 package loader
 
 import (
-	"bytes"
 	"crypto/sha256"
-	"errors"
 	"fmt"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
-	"github.com/gabriel-vasile/mimetype"
 )
 
 func NewS3BackendFromConfig(config *S3Config) (*S3Backend, error) {
@@ -95,6 +93,15 @@ func NewS3Backend(accessKey string, secretKey string, region string, server stri
 }
 
 func (b *S3Backend) Load() ([]byte, error) {
+	data, err := b.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	return decodeKubeconfig(data, b.config.DecryptKey, fmt.Sprintf("s3://%s/%s/%s", b.config.Server, b.config.Bucket, b.config.Path))
+}
+
+// Fetch downloads the configured object without any further processing
+func (b *S3Backend) Fetch() ([]byte, error) {
 	if b.Downloader == nil {
 		return nil, fmt.Errorf("no s3 client configured")
 	}
@@ -123,39 +130,19 @@ func (b *S3Backend) Load() ([]byte, error) {
 		Bucket: aws.String(b.config.Bucket),
 		Key:    aws.String(b.config.Path),
 	}
+	if b.config.Version != "" {
+		requestInput.VersionId = aws.String(b.config.Version)
+	}
 
 	buf := aws.NewWriteAtBuffer([]byte{})
 	_, err := b.Downloader.Download(buf, &requestInput)
 	if err != nil {
-		return nil, err
-	}
-	data := buf.Bytes()
-
-	mime, err := mimetype.DetectReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, fmt.Errorf("failed to detect mimetype for s3://%s/%s/%s", b.config.Server, b.config.Bucket, b.config.Path)
-	}
-
-	var rawKubeconfig []byte
-
-	if mime.Is("text/plain") {
-		rawKubeconfig = data
-	} else if mime.Is("application/x-7z-compressed") {
-		rawKubeconfig, err = extractSingleTar7Zip(data, b.config.DecryptKey)
-		if err != nil {
-			return nil, err
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, &NotFoundError{Err: err}
 		}
-	} else if mime.Is("application/octet-stream") {
-		rawKubeconfig, err = decryptOpensslSymmetric(data, b.config.DecryptKey)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, errors.New("Unknown kubeconfig source file type: " + mime.String())
+		return nil, err
 	}
-
-	return rawKubeconfig, nil
-
+	return buf.Bytes(), nil
 }
 
 func (b *S3Backend) Type() string {
@@ -176,6 +163,7 @@ func (c *S3Config) Sanitize() BackendConfig {
 		DecryptKey: fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(c.DecryptKey))),
 		Bucket:     c.Bucket,
 		Path:       c.Path,
+		Version:    c.Version,
 	}
 
 	return result
@@ -17,6 +17,8 @@ limitations under the License.
 package loader
 
 import (
+	"net/http"
+
 	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
 	"sigs.k8s.io/yaml"
 )
@@ -28,6 +30,12 @@ type Loader interface {
 	Config() BackendConfig // returns the backend config of the loader
 }
 
+// NotFoundError is returned by the loader backends if the requested
+// file / object does not exist, it wraps the original error
+type NotFoundError struct {
+	Err error
+}
+
 type BackendConfig interface {
 	Yaml(unsafe bool) ([]byte, error) // returns the sanitized loader config as yaml
 	Sanitize() BackendConfig          // returns the sanitized loader config
@@ -41,6 +49,9 @@ type S3Config struct {
 	DecryptKey string `json:"decrypt_key"`
 	Bucket     string `json:"bucket"`
 	Path       string `json:"path"`
+	// Version is the version id of the object, the latest
+	// version is used if it is empty
+	Version string `json:"version,omitempty"`
 }
 
 type S3Backend struct {
@@ -48,6 +59,16 @@ type S3Backend struct {
 	Downloader s3manageriface.DownloaderAPI
 }
 
+type HTTPConfig struct {
+	URL        string `json:"url"`
+	DecryptKey string `json:"decrypt_key"`
+}
+
+type HTTPBackend struct {
+	config *HTTPConfig
+	Client *http.Client
+}
+
 type FileConfig struct {
 	Path       string `json:"path"`
 	DecryptKey string `json:"decrypt_key"`
@@ -20,6 +20,7 @@ import (
 	"archive/tar"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -30,6 +31,44 @@ import (
 	"github.com/mitchellh/mapstructure"
 )
 
+func (e *NotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound returns true if the given error reports that the file / object
+// to load does not exist
+func IsNotFound(err error) bool {
+	if os.IsNotExist(err) {
+		return true
+	}
+	var notFound *NotFoundError
+	return errors.As(err, &notFound)
+}
+
+// decodeKubeconfig returns the kubeconfig contained in the given data,
+// which is either plain text, a 7zip archive containing a single tar
+// archive with the kubeconfig or openssl encrypted. The given name
+// describes the source of the data in errors.
+func decodeKubeconfig(data []byte, decryptKey string, name string) ([]byte, error) {
+	mime, err := mimetype.DetectReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect mimetype for %s", name)
+	}
+
+	if mime.Is("text/plain") {
+		return data, nil
+	} else if mime.Is("application/x-7z-compressed") {
+		return extractSingleTar7Zip(data, decryptKey)
+	} else if mime.Is("application/octet-stream") {
+		return decryptOpensslSymmetric(data, decryptKey)
+	}
+	return nil, errors.New("Unknown kubeconfig source file type: " + mime.String())
+}
+
 func extractSingleTar7Zip(data []byte, password string) ([]byte, error) {
 	// extracting 7zip data only works with files stored in the filesystem
 	tmpfile, err := ioutil.TempFile("", "s3loader")
@@ -20,7 +20,6 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
-	"os"
 
 	"sigs.k8s.io/yaml"
 )
@@ -31,31 +30,52 @@ import (
 // Duh!
 
 // NewBaseConfigFromFile loads a playbook base config from the given
-// file path. The file must contain yaml data.
+// file path. The file must contain yaml data. Imported playbooks are
+// resolved relative to the directory of the file.
 func NewBaseConfigFromFile(path string) (*BaseConfig, error) {
-	file, err := os.Open(path)
+	return NewBaseConfigFromFiles([]string{path})
+}
+
+// NewBaseConfigFromFiles loads the plays of all given playbook files
+// (in the given order) into one base config. Plays of different files
+// must not share a name.
+func NewBaseConfigFromFiles(paths []string) (*BaseConfig, error) {
+	result := &BaseConfig{Plays: []*BasePlay{}}
+	for _, path := range paths {
+		plays, err := loadPlays(path, []string{})
+		if err != nil {
+			return nil, err
+		}
+		result.Plays = append(result.Plays, plays...)
+	}
+
+	err := result.check()
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	return NewBaseConfigFromReader(file)
+	return result, nil
 }
 
 // NewBaseConfigFromReader loads a playbook base config from the given
-// bufio.Reader. The reader must point to yaml data.
+// bufio.Reader. The reader must point to yaml data. As there is no file
+// to resolve them against, relative imports are rejected.
 func NewBaseConfigFromReader(reader io.Reader) (*BaseConfig, error) {
-	data := []byte{}
-	var result BaseConfig
 	data, err := ioutil.ReadAll(reader)
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal(data, &result)
+	plays, err := parsePlays(data, "", "", []string{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BaseConfig{Plays: plays}
+	err = result.check()
 	if err != nil {
 		return nil, err
 	}
-	return &result, nil
+	return result, nil
 }
 
 // Applicable returns a BaseConfig that contains only the plays where
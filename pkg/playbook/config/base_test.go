@@ -18,7 +18,9 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"gotest.tools/assert"
@@ -112,3 +114,116 @@ func TestBaseConfigFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestBaseConfigImports(t *testing.T) {
+	tests := map[string]struct {
+		paths       []string
+		names       []string
+		files       []string
+		errExpected bool
+	}{
+		"imports": {
+			paths: []string{"testdata/imports/playbook.yml"},
+			names: []string{"first", "base", "monitoring", "last", "team"},
+			files: []string{
+				"testdata/imports/playbook.yml",
+				"testdata/imports/common/01-base.yml",
+				"testdata/imports/common/02-monitoring.yml",
+				"testdata/imports/playbook.yml",
+				"testdata/imports/team.yml",
+			},
+		},
+		"multiple-files": {
+			paths: []string{"testdata/imports/team.yml", "testdata/playbook.yml"},
+			names: []string{"team", "test01", "test02", "test03", "regexp-test"},
+			files: []string{
+				"testdata/imports/team.yml",
+				"testdata/playbook.yml",
+				"testdata/playbook.yml",
+				"testdata/playbook.yml",
+				"testdata/playbook.yml",
+			},
+		},
+		"duplicate-single-file": {
+			paths: []string{"testdata/imports/duplicate/single.yml"},
+			names: []string{"base", "base"},
+			files: []string{
+				"testdata/imports/duplicate/single.yml",
+				"testdata/imports/duplicate/single.yml",
+			},
+		},
+		"duplicate": {
+			paths:       []string{"testdata/imports/playbook.yml", "testdata/imports/duplicate/playbook.yml"},
+			errExpected: true,
+		},
+		"cycle": {
+			paths:       []string{"testdata/imports/cycle/a.yml"},
+			errExpected: true,
+		},
+		"invalid": {
+			paths:       []string{"testdata/imports/invalid.yml"},
+			errExpected: true,
+		},
+		"missing": {
+			paths:       []string{"testdata/imports/missing.yml"},
+			errExpected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			config, err := NewBaseConfigFromFiles(tc.paths)
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+
+			names := []string{}
+			files := []string{}
+			for _, play := range config.Plays {
+				names = append(names, play.Name)
+				files = append(files, play.File)
+			}
+			assert.DeepEqual(t, tc.names, names)
+			assert.DeepEqual(t, tc.files, files)
+		})
+	}
+}
+
+func TestBaseConfigFromReaderImports(t *testing.T) {
+	abs, err := filepath.Abs("testdata/imports/team.yml")
+	assert.NilError(t, err)
+
+	tests := map[string]struct {
+		playbook    string
+		names       []string
+		errExpected bool
+	}{
+		"absolute": {
+			playbook: fmt.Sprintf("plays:\n  - import_playbook: %s\n", abs),
+			names:    []string{"team"},
+		},
+		"relative": {
+			playbook:    "plays:\n  - import_playbook: testdata/imports/team.yml\n",
+			errExpected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			config, err := NewBaseConfigFromReader(strings.NewReader(tc.playbook))
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+
+			names := []string{}
+			for _, play := range config.Plays {
+				names = append(names, play.Name)
+			}
+			assert.DeepEqual(t, tc.names, names)
+		})
+	}
+}
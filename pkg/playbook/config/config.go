@@ -35,6 +35,7 @@ func decode(data *map[string]interface{}, result interface{}) error {
 		ErrorUnused:      false,
 		WeaklyTypedInput: true,
 		TagName:          "json",
+		DecodeHook:       conditionDecodeHook,
 		Result:           &result,
 	}
 
@@ -0,0 +1,213 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlayLevels sorts the plays by their dependencies (see Play.DependsOn) into
+// levels. The plays of a level only depend on plays of previous levels and
+// keep the order of the config. Dependencies on plays that are not part of
+// the config (e.g. plays not applicable to the groups of an inventory entry)
+// are ignored.
+func (c *Config) PlayLevels() ([][]*Play, error) {
+	names := make([]string, len(c.Plays))
+	dependencies := make([][]string, len(c.Plays))
+	for i, play := range c.Plays {
+		names[i] = play.Name
+		dependencies[i] = play.DependsOn
+	}
+
+	levels, err := sortLevels("play", names, dependencies, true)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([][]*Play, len(levels))
+	for i, level := range levels {
+		for _, id := range level {
+			result[i] = append(result[i], c.Plays[id])
+		}
+	}
+	return result, nil
+}
+
+// OrderedPlays returns the plays in an order that satisfies their
+// dependencies, see PlayLevels()
+func (c *Config) OrderedPlays() ([]*Play, error) {
+	levels, err := c.PlayLevels()
+	if err != nil {
+		return nil, err
+	}
+
+	result := []*Play{}
+	for _, level := range levels {
+		result = append(result, level...)
+	}
+	return result, nil
+}
+
+// ChartLevels sorts the charts of the play by their dependencies (see
+// Chart.DependsOn) into levels. The charts of a level only depend on
+// charts of previous levels and can be deployed in parallel.
+func (p *Play) ChartLevels() ([][]*Chart, error) {
+	names := make([]string, len(p.Charts))
+	dependencies := make([][]string, len(p.Charts))
+	for i, chart := range p.Charts {
+		names[i] = chart.Name
+		dependencies[i] = chart.DependsOn
+	}
+
+	levels, err := sortLevels("chart", names, dependencies, false)
+	if err != nil {
+		return nil, fmt.Errorf("play '%s': %s", p.Name, err)
+	}
+
+	result := make([][]*Chart, len(levels))
+	for i, level := range levels {
+		for _, id := range level {
+			result[i] = append(result[i], p.Charts[id])
+		}
+	}
+	return result, nil
+}
+
+// Required returns the names of the plays other plays depend on
+func (c *Config) Required() map[string]bool {
+	result := map[string]bool{}
+	for _, play := range c.Plays {
+		for _, dep := range play.DependsOn {
+			result[dep] = true
+		}
+	}
+	return result
+}
+
+// Required returns the names of the charts other charts of the play depend on
+func (p *Play) Required() map[string]bool {
+	result := map[string]bool{}
+	for _, chart := range p.Charts {
+		for _, dep := range chart.DependsOn {
+			result[dep] = true
+		}
+	}
+	return result
+}
+
+// CheckDependencies returns an error if the dependencies of the plays or
+// the dependencies of the charts of any play cannot be resolved
+func (c *Config) CheckDependencies() error {
+	_, err := c.PlayLevels()
+	if err != nil {
+		return err
+	}
+	for _, play := range c.Plays {
+		_, err := play.ChartLevels()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDependencies returns an error if a play depends on an unknown
+// play or if the dependencies of the plays contain a cycle
+func (bc *BaseConfig) checkDependencies() error {
+	names := make([]string, len(bc.Plays))
+	dependencies := make([][]string, len(bc.Plays))
+	for i, play := range bc.Plays {
+		names[i] = play.Name
+		dependencies[i] = play.DependsOn
+	}
+
+	_, err := sortLevels("play", names, dependencies, false)
+	return err
+}
+
+// sortLevels sorts the given nodes topologically into levels of node
+// indices. The nodes of a level only depend on nodes of previous levels
+// and keep their given order. Dependencies on unknown nodes are an error
+// unless ignoreUnknown is set.
+func sortLevels(kind string, names []string, dependencies [][]string, ignoreUnknown bool) ([][]int, error) {
+	// names are not necessarily unique, a dependency on
+	// a name is a dependency on all nodes of that name
+	index := map[string][]int{}
+	for i, name := range names {
+		if name == "" {
+			continue
+		}
+		index[name] = append(index[name], i)
+	}
+
+	// resolve the dependencies to node indices
+	edges := make([][]int, len(names))
+	for i, deps := range dependencies {
+		for _, dep := range deps {
+			ids, ok := index[dep]
+			if !ok {
+				if ignoreUnknown {
+					continue
+				}
+				return nil, fmt.Errorf("%s '%s' depends on unknown %s '%s'", kind, names[i], kind, dep)
+			}
+			edges[i] = append(edges[i], ids...)
+		}
+	}
+
+	result := [][]int{}
+	done := make([]bool, len(names))
+	remaining := len(names)
+	for remaining > 0 {
+		level := []int{}
+		for i := range names {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for _, id := range edges[i] {
+				if !done[id] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				level = append(level, i)
+			}
+		}
+
+		// no node without pending dependencies left, all
+		// remaining nodes are part of or depend on a cycle
+		if len(level) <= 0 {
+			cycle := []string{}
+			for i, name := range names {
+				if !done[i] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle in the %ss '%s'", kind, strings.Join(cycle, "', '"))
+		}
+
+		for _, id := range level {
+			done[id] = true
+		}
+		remaining = remaining - len(level)
+		result = append(result, level)
+	}
+	return result, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestChartLevels(t *testing.T) {
+	tests := map[string]struct {
+		charts      []*Chart
+		expected    [][]string
+		errExpected bool
+	}{
+		"no-dependencies": {
+			charts:   []*Chart{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			expected: [][]string{{"a", "b", "c"}},
+		},
+		"chain": {
+			charts:   []*Chart{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"c"}}, {Name: "c"}},
+			expected: [][]string{{"c"}, {"b"}, {"a"}},
+		},
+		"diamond": {
+			charts: []*Chart{
+				{Name: "ingress", DependsOn: []string{"cert-manager", "dns"}},
+				{Name: "cert-manager", DependsOn: []string{"crds"}},
+				{Name: "dns", DependsOn: []string{"crds"}},
+				{Name: "crds"},
+				{Name: "monitoring"},
+			},
+			expected: [][]string{{"crds", "monitoring"}, {"cert-manager", "dns"}, {"ingress"}},
+		},
+		"unknown": {
+			charts:      []*Chart{{Name: "a", DependsOn: []string{"unknown"}}},
+			errExpected: true,
+		},
+		"self": {
+			charts:      []*Chart{{Name: "a", DependsOn: []string{"a"}}},
+			errExpected: true,
+		},
+		"cycle": {
+			charts:      []*Chart{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "c"}},
+			errExpected: true,
+		},
+		"duplicate": {
+			charts:   []*Chart{{Name: "b", DependsOn: []string{"a"}}, {Name: "a"}, {Name: "a"}},
+			expected: [][]string{{"a", "a"}, {"b"}},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			play := &Play{Name: "play", Charts: tc.charts}
+			levels, err := play.ChartLevels()
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+
+			got := [][]string{}
+			for _, level := range levels {
+				names := []string{}
+				for _, chart := range level {
+					names = append(names, chart.Name)
+				}
+				got = append(got, names)
+			}
+			assert.DeepEqual(t, tc.expected, got)
+		})
+	}
+}
+
+func TestRequiredCharts(t *testing.T) {
+	play := &Play{
+		Charts: []*Chart{
+			{Name: "ingress", DependsOn: []string{"cert-manager", "dns"}},
+			{Name: "cert-manager", DependsOn: []string{"crds"}},
+			{Name: "dns", DependsOn: []string{"crds"}},
+			{Name: "crds"},
+			{Name: "monitoring"},
+		},
+	}
+	assert.DeepEqual(t, map[string]bool{"cert-manager": true, "dns": true, "crds": true}, play.Required())
+}
+
+func TestOrderedPlays(t *testing.T) {
+	config := &Config{
+		Plays: []*Play{
+			{Name: "ingress", DependsOn: []string{"cert-manager"}},
+			{Name: "monitoring", DependsOn: []string{"not-applicable"}},
+			{Name: "cert-manager"},
+		},
+	}
+
+	// dependencies on plays not part of the config are ignored
+	plays, err := config.OrderedPlays()
+	assert.NilError(t, err)
+	names := []string{}
+	for _, play := range plays {
+		names = append(names, play.Name)
+	}
+	assert.DeepEqual(t, []string{"monitoring", "cert-manager", "ingress"}, names)
+	assert.NilError(t, config.CheckDependencies())
+	assert.DeepEqual(t, map[string]bool{"cert-manager": true, "not-applicable": true}, config.Required())
+
+	config.Plays[2].DependsOn = []string{"ingress"}
+	_, err = config.OrderedPlays()
+	assert.Assert(t, err != nil)
+	assert.Assert(t, config.CheckDependencies() != nil)
+}
+
+func TestBaseConfigDependencies(t *testing.T) {
+	for _, path := range []string{"testdata/dependencies/unknown.yml", "testdata/dependencies/cycle.yml"} {
+		_, err := NewBaseConfigFromFile(path)
+		assert.Assert(t, err != nil, path)
+	}
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// loadPlays reads the plays of the given playbook file and resolves
+// its imports. The stack holds the files currently being imported
+// to detect import cycles.
+func loadPlays(path string, stack []string) ([]*BasePlay, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range stack {
+		if file == abs {
+			return nil, fmt.Errorf("playbook import cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fileStack := make([]string, len(stack), len(stack)+1)
+	copy(fileStack, stack)
+	return parsePlays(data, path, filepath.Dir(path), append(fileStack, abs))
+}
+
+// parsePlays parses the given playbook data and replaces all import_playbook
+// and include entries with the plays of the imported files (in place, to
+// preserve the order of the plays). Relative imports are resolved relative
+// to dir (and rejected if dir is empty) and can contain glob patterns
+// (see filepath.Match).
+func parsePlays(data []byte, file string, dir string, stack []string) ([]*BasePlay, error) {
+	var config BaseConfig
+	err := yaml.Unmarshal(data, &config)
+	if err != nil {
+		if file != "" {
+			return nil, fmt.Errorf("failed to parse playbook %s: %s", file, err)
+		}
+		return nil, err
+	}
+
+	result := []*BasePlay{}
+	for _, play := range config.Plays {
+		pattern := play.ImportPlaybook
+		if pattern == "" {
+			pattern = play.Include
+		}
+		if pattern == "" {
+			play.File = file
+			result = append(result, play)
+			continue
+		}
+
+		if (play.ImportPlaybook != "" && play.Include != "") || play.Name != "" || len(play.Groups) > 0 || play.Charts != nil || play.Repos != nil || len(play.DependsOn) > 0 || play.When != "" {
+			return nil, fmt.Errorf("playbook import '%s' in %s must not have any other fields", pattern, playbookFile(file))
+		}
+
+		if !filepath.IsAbs(pattern) {
+			if dir == "" {
+				return nil, fmt.Errorf("relative playbook import '%s' in %s cannot be resolved without a playbook file", pattern, playbookFile(file))
+			}
+			pattern = filepath.Join(dir, pattern)
+		}
+		// the matches are sorted, so the order of the imported plays is stable
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid playbook import '%s' in %s: %s", pattern, playbookFile(file), err)
+		}
+		if len(matches) <= 0 {
+			return nil, fmt.Errorf("playbook import '%s' in %s does not match any file", pattern, playbookFile(file))
+		}
+
+		for _, match := range matches {
+			plays, err := loadPlays(match, stack)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, plays...)
+		}
+	}
+	return result, nil
+}
+
+// check validates the plays of the base config
+func (bc *BaseConfig) check() error {
+	err := bc.checkDuplicates()
+	if err != nil {
+		return err
+	}
+	return bc.checkDependencies()
+}
+
+// checkDuplicates returns an error if plays of different playbook files
+// share the same name. Plays of the same name in a single file are
+// accepted as they always were.
+func (bc *BaseConfig) checkDuplicates() error {
+	seen := map[string]*BasePlay{}
+	for _, play := range bc.Plays {
+		if play.Name == "" {
+			continue
+		}
+		if other, ok := seen[play.Name]; ok && other.File != play.File {
+			return fmt.Errorf("duplicate play '%s' in %s and %s", play.Name, playbookFile(other.File), playbookFile(play.File))
+		}
+		seen[play.Name] = play
+	}
+	return nil
+}
+
+// playbookFile returns a printable name of the given playbook file
+func playbookFile(file string) string {
+	if file == "" {
+		return "<playbook>"
+	}
+	return file
+}
@@ -41,6 +41,11 @@ type Play struct {
 	Groups []string `json:"groups"`
 	Charts []*Chart `json:"charts"`
 	Repos  []*Repo  `json:"repos"`
+	// DependsOn lists the plays that must be deployed before this play
+	DependsOn []string `json:"depends_on,omitempty"`
+	// When is a condition (see EvalCondition()), the play is skipped
+	// if it is false
+	When Condition `json:"when,omitempty"`
 }
 
 // BasePlay holds the same information as a play,
@@ -53,6 +58,15 @@ type BasePlay struct {
 	Groups []string         `json:"groups"`
 	Charts *json.RawMessage `json:"charts,omitempty"`
 	Repos  *json.RawMessage `json:"repos,omitempty"`
+	// DependsOn lists the plays that must be deployed before this play
+	DependsOn []string  `json:"depends_on,omitempty"`
+	When      Condition `json:"when,omitempty"`
+	// ImportPlaybook (or its alias Include) replaces the play with the
+	// plays of the given playbook file(s), see NewBaseConfigFromFiles()
+	ImportPlaybook string `json:"import_playbook,omitempty"`
+	Include        string `json:"include,omitempty"`
+	// File is the playbook file the play was loaded from (if any)
+	File string `json:"-"`
 }
 
 // Chart holds all information to deploy a helm chart
@@ -63,6 +77,24 @@ type Chart struct {
 	Version   string                 `json:"version"`
 	Namespace string                 `json:"namespace"`
 	Values    map[string]interface{} `json:"values"`
+	// DependsOn lists the charts of the same play that must be
+	// deployed before this chart
+	DependsOn []string `json:"depends_on,omitempty"`
+	// When is a condition (see EvalCondition()), the chart is skipped
+	// if it is false
+	When Condition `json:"when,omitempty"`
+}
+
+// Condition is a when condition, see EvalCondition(). Besides strings,
+// booleans (e.g. the result of a spruce operator) are accepted.
+type Condition string
+
+// Skipped is a play or a chart of a play that was skipped because
+// its when condition is false
+type Skipped struct {
+	Play *Play
+	// Chart is nil if the whole play was skipped
+	Chart *Chart
 }
 
 // Repo represents a helm chart repository
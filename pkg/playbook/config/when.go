@@ -0,0 +1,585 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+EvalCondition evaluates the given when condition against the given data.
+An empty condition is always true. Conditions support
+
+  - value references (e.g. vars.FEATURES.logging, list elements are
+    referenced by their index, e.g. vars.list.0)
+  - string ('a' or "a"), number, boolean (true, false) and null literals
+  - comparisons (==, !=, <, <=, >, >=)
+  - "in" / "not in" to check if a list contains an element, a map contains
+    a key or a string contains a substring
+  - "is defined" / "is not defined" to check if a value reference exists
+  - "and", "or", "not" and parentheses
+
+Referencing values that do not exist is an error (except for "is defined").
+Values are true if they are not false, null, zero or empty.
+*/
+func EvalCondition(condition string, data map[string]interface{}) (bool, error) {
+	if strings.TrimSpace(condition) == "" {
+		return true, nil
+	}
+
+	tokens, err := tokenize(condition)
+	if err != nil {
+		return false, err
+	}
+
+	p := &conditionParser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if p.pos < len(p.tokens) {
+		return false, fmt.Errorf("unexpected '%s'", p.tokens[p.pos].text)
+	}
+
+	value, err := expr(data)
+	if err != nil {
+		return false, err
+	}
+	return truthy(value), nil
+}
+
+// ApplyConditions removes all plays and charts whose when condition (see
+// EvalCondition()) is false from the config and returns them. Dependencies
+// of the remaining charts on skipped charts are dropped.
+func (c *Config) ApplyConditions(data map[string]interface{}) ([]*Skipped, error) {
+	result := []*Skipped{}
+	plays := []*Play{}
+	for _, play := range c.Plays {
+		ok, err := EvalCondition(string(play.When), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate condition '%s' of play '%s': %s", play.When, play.Name, err)
+		}
+		if !ok {
+			result = append(result, &Skipped{Play: play})
+			continue
+		}
+
+		charts := []*Chart{}
+		skippedCharts := map[string]bool{}
+		for _, chart := range play.Charts {
+			ok, err := EvalCondition(string(chart.When), data)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate condition '%s' of chart '%s' in play '%s': %s", chart.When, chart.Name, play.Name, err)
+			}
+			if !ok {
+				result = append(result, &Skipped{Play: play, Chart: chart})
+				skippedCharts[chart.Name] = true
+				continue
+			}
+			charts = append(charts, chart)
+		}
+
+		if len(skippedCharts) > 0 {
+			for _, chart := range charts {
+				dependsOn := []string{}
+				for _, dep := range chart.DependsOn {
+					if !skippedCharts[dep] {
+						dependsOn = append(dependsOn, dep)
+					}
+				}
+				chart.DependsOn = dependsOn
+			}
+		}
+		play.Charts = charts
+		plays = append(plays, play)
+	}
+	c.Plays = plays
+	return result, nil
+}
+
+// UnmarshalJSON decodes strings and booleans into a condition
+func (c *Condition) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	err := json.Unmarshal(data, &value)
+	if err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case nil:
+		*c = ""
+	case string:
+		*c = Condition(v)
+	case bool:
+		*c = Condition(strconv.FormatBool(v))
+	default:
+		return fmt.Errorf("invalid condition '%s': must be a string or a boolean", string(data))
+	}
+	return nil
+}
+
+// conditionDecodeHook decodes booleans into conditions instead of the
+// weakly typed "1" / "0" of mapstructure
+func conditionDecodeHook(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(Condition("")) {
+		return data, nil
+	}
+	if b, ok := data.(bool); ok {
+		return strconv.FormatBool(b), nil
+	}
+	return data, nil
+}
+
+type tokenType int
+
+const (
+	tokenOperator tokenType = iota
+	tokenString
+	tokenWord
+)
+
+type token struct {
+	kind tokenType
+	text string
+}
+
+// conditionExpr returns the value of a (sub) condition for the given data
+type conditionExpr func(data map[string]interface{}) (interface{}, error)
+
+type conditionParser struct {
+	tokens []token
+	pos    int
+}
+
+var conditionOperators = []string{"==", "!=", "<=", ">=", "<", ">", "(", ")"}
+
+func tokenize(condition string) ([]token, error) {
+	result := []token{}
+	i := 0
+	for i < len(condition) {
+		c := condition[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			text := strings.Builder{}
+			j := i + 1
+			for ; j < len(condition) && condition[j] != c; j++ {
+				if condition[j] == '\\' && j+1 < len(condition) {
+					j++
+				}
+				text.WriteByte(condition[j])
+			}
+			if j >= len(condition) {
+				return nil, fmt.Errorf("unterminated string starting at position %d", i+1)
+			}
+			result = append(result, token{kind: tokenString, text: text.String()})
+			i = j + 1
+		default:
+			operator := ""
+			for _, op := range conditionOperators {
+				if strings.HasPrefix(condition[i:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator != "" {
+				result = append(result, token{kind: tokenOperator, text: operator})
+				i = i + len(operator)
+				continue
+			}
+
+			j := i
+			for j < len(condition) && !strings.ContainsRune(" \t\n\r'\"=!<>()", rune(condition[j])) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", c, i+1)
+			}
+			result = append(result, token{kind: tokenWord, text: condition[i:j]})
+			i = j
+		}
+	}
+	return result, nil
+}
+
+func (p *conditionParser) peek(kind tokenType, text string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == kind && p.tokens[p.pos].text == text
+}
+
+func (p *conditionParser) expect(kind tokenType, text string) error {
+	if !p.peek(kind, text) {
+		if p.pos < len(p.tokens) {
+			return fmt.Errorf("expected '%s' but got '%s'", text, p.tokens[p.pos].text)
+		}
+		return fmt.Errorf("expected '%s' at the end of the condition", text)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *conditionParser) parseOr() (conditionExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(tokenWord, "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(data map[string]interface{}) (interface{}, error) {
+			value, err := l(data)
+			if err != nil || truthy(value) {
+				return true, err
+			}
+			value, err = right(data)
+			return truthy(value), err
+		}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionExpr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek(tokenWord, "and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(data map[string]interface{}) (interface{}, error) {
+			value, err := l(data)
+			if err != nil || !truthy(value) {
+				return false, err
+			}
+			value, err = right(data)
+			return truthy(value), err
+		}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (conditionExpr, error) {
+	if p.peek(tokenWord, "not") {
+		p.pos++
+		expr, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			value, err := expr(data)
+			return !truthy(value), err
+		}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *conditionParser) parseComparison() (conditionExpr, error) {
+	left, path, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	// <path> is [not] defined
+	if p.peek(tokenWord, "is") {
+		p.pos++
+		negate := p.peek(tokenWord, "not")
+		if negate {
+			p.pos++
+		}
+		if err := p.expect(tokenWord, "defined"); err != nil {
+			return nil, err
+		}
+		if path == "" {
+			return nil, fmt.Errorf("'is defined' requires a value reference")
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			_, ok := lookup(data, path)
+			return ok != negate, nil
+		}, nil
+	}
+
+	// <operand> [not] in <operand>
+	negate := p.peek(tokenWord, "not") && p.pos+1 < len(p.tokens) && p.tokens[p.pos+1].kind == tokenWord && p.tokens[p.pos+1].text == "in"
+	if negate {
+		p.pos++
+	}
+	if p.peek(tokenWord, "in") {
+		p.pos++
+		right, _, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return func(data map[string]interface{}) (interface{}, error) {
+			l, r, err := evalOperands(left, right, data)
+			if err != nil {
+				return nil, err
+			}
+			ok, err := contains(r, l)
+			return ok != negate, err
+		}, nil
+	}
+
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.peek(tokenOperator, op) {
+			continue
+		}
+		p.pos++
+		right, _, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		op := op
+		return func(data map[string]interface{}) (interface{}, error) {
+			l, r, err := evalOperands(left, right, data)
+			if err != nil {
+				return nil, err
+			}
+			return compare(op, l, r)
+		}, nil
+	}
+	return left, nil
+}
+
+// parseOperand returns the expression of the next operand and, if the
+// operand is a value reference, its path
+func (p *conditionParser) parseOperand() (conditionExpr, string, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, "", fmt.Errorf("unexpected end of condition")
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+
+	constant := func(value interface{}) conditionExpr {
+		return func(data map[string]interface{}) (interface{}, error) {
+			return value, nil
+		}
+	}
+
+	switch tok.kind {
+	case tokenString:
+		return constant(tok.text), "", nil
+	case tokenOperator:
+		if tok.text != "(" {
+			return nil, "", fmt.Errorf("unexpected '%s'", tok.text)
+		}
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, "", err
+		}
+		return expr, "", p.expect(tokenOperator, ")")
+	}
+
+	switch tok.text {
+	case "true":
+		return constant(true), "", nil
+	case "false":
+		return constant(false), "", nil
+	case "null":
+		return constant(nil), "", nil
+	case "and", "or", "not", "in", "is", "defined":
+		return nil, "", fmt.Errorf("unexpected '%s'", tok.text)
+	}
+
+	if number, err := strconv.ParseFloat(tok.text, 64); err == nil {
+		return constant(number), "", nil
+	}
+
+	path := tok.text
+	return func(data map[string]interface{}) (interface{}, error) {
+		value, ok := lookup(data, path)
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not defined", path)
+		}
+		return value, nil
+	}, path, nil
+}
+
+func evalOperands(left conditionExpr, right conditionExpr, data map[string]interface{}) (interface{}, interface{}, error) {
+	l, err := left(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := right(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return l, r, nil
+}
+
+// lookup returns the value at the given dot separated path
+func lookup(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case map[interface{}]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			id, err := strconv.Atoi(key)
+			if err != nil || id < 0 || id >= len(node) {
+				return nil, false
+			}
+			current = node[id]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func truthy(value interface{}) bool {
+	if value == nil {
+		return false
+	}
+	if b, ok := value.(bool); ok {
+		return b
+	}
+	if number, ok := toNumber(value); ok {
+		if _, isString := value.(string); !isString {
+			return number != 0
+		}
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map:
+		return v.Len() > 0
+	}
+	return true
+}
+
+// toNumber converts numbers and numeric strings to float64
+func toNumber(value interface{}) (float64, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		number, err := strconv.ParseFloat(v.String(), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// numbers returns both values as numbers if they are numbers. Numeric
+// strings are only treated as numbers if the other value is a number.
+func numbers(l interface{}, r interface{}) (float64, float64, bool) {
+	_, lString := l.(string)
+	_, rString := r.(string)
+	if lString && rString {
+		return 0, 0, false
+	}
+	lNumber, lok := toNumber(l)
+	rNumber, rok := toNumber(r)
+	return lNumber, rNumber, lok && rok
+}
+
+func equal(l interface{}, r interface{}) bool {
+	if lNumber, rNumber, ok := numbers(l, r); ok {
+		return lNumber == rNumber
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+func compare(op string, l interface{}, r interface{}) (bool, error) {
+	switch op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	}
+
+	var cmp int
+	if lNumber, rNumber, ok := numbers(l, r); ok {
+		switch {
+		case lNumber < rNumber:
+			cmp = -1
+		case lNumber > rNumber:
+			cmp = 1
+		}
+	} else {
+		lString, lok := l.(string)
+		rString, rok := r.(string)
+		if !lok || !rok {
+			return false, fmt.Errorf("cannot compare '%v' and '%v' with '%s'", l, r, op)
+		}
+		cmp = strings.Compare(lString, rString)
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// contains checks if the given list contains the given element, the
+// given map contains the given key or the given string contains the
+// given substring
+func contains(container interface{}, element interface{}) (bool, error) {
+	switch c := container.(type) {
+	case []interface{}:
+		for _, item := range c {
+			if equal(item, element) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		_, ok := c[fmt.Sprint(element)]
+		return ok, nil
+	case map[interface{}]interface{}:
+		_, ok := c[element]
+		return ok, nil
+	case string:
+		s, ok := element.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot check if string '%s' contains '%v'", c, element)
+		}
+		return strings.Contains(c, s), nil
+	}
+	return false, fmt.Errorf("cannot check if '%v' contains '%v'", container, element)
+}
@@ -0,0 +1,156 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+	"sigs.k8s.io/yaml"
+)
+
+func TestEvalCondition(t *testing.T) {
+	var data map[string]interface{}
+	err := yaml.Unmarshal([]byte(`
+vars:
+  enabled: true
+  disabled: false
+  empty: ""
+  zero: 0
+  replicas: 3
+  env: prod
+  list: [a, b]
+  map:
+    key: value
+facts:
+  kubernetes:
+    minor: "20"
+  crds: [certificates.cert-manager.io]
+`), &data)
+	assert.NilError(t, err)
+
+	tests := map[string]struct {
+		condition   string
+		expected    bool
+		errExpected bool
+	}{
+		"empty":             {condition: "", expected: true},
+		"reference-true":    {condition: "vars.enabled", expected: true},
+		"reference-false":   {condition: "vars.disabled", expected: false},
+		"reference-empty":   {condition: "vars.empty", expected: false},
+		"reference-zero":    {condition: "vars.zero", expected: false},
+		"reference-list":    {condition: "vars.list", expected: true},
+		"list-index":        {condition: "vars.list.1 == 'b'", expected: true},
+		"not":               {condition: "not vars.disabled", expected: true},
+		"equal-string":      {condition: "vars.env == 'prod'", expected: true},
+		"equal-double":      {condition: `vars.env == "prod"`, expected: true},
+		"not-equal":         {condition: "vars.env != 'prod'", expected: false},
+		"equal-number":      {condition: "vars.replicas == 3", expected: true},
+		"greater":           {condition: "vars.replicas > 2", expected: true},
+		"less-equal":        {condition: "vars.replicas <= 2", expected: false},
+		"numeric-string":    {condition: "facts.kubernetes.minor >= 19", expected: true},
+		"string-compare":    {condition: "'a' < 'b'", expected: true},
+		"in-list":           {condition: "'certificates.cert-manager.io' in facts.crds", expected: true},
+		"not-in-list":       {condition: "'issuers.cert-manager.io' not in facts.crds", expected: true},
+		"in-map":            {condition: "'key' in vars.map", expected: true},
+		"in-string":         {condition: "'ro' in vars.env", expected: true},
+		"defined":           {condition: "vars.env is defined", expected: true},
+		"not-defined":       {condition: "vars.missing is not defined", expected: true},
+		"and":               {condition: "vars.enabled and vars.env == 'prod'", expected: true},
+		"or":                {condition: "vars.disabled or vars.replicas > 5", expected: false},
+		"precedence":        {condition: "vars.enabled or vars.disabled and vars.disabled", expected: true},
+		"parentheses":       {condition: "(vars.enabled or vars.disabled) and vars.disabled", expected: false},
+		"short-circuit":     {condition: "vars.missing is defined and vars.missing", expected: false},
+		"null":              {condition: "vars.env != null", expected: true},
+		"undefined":         {condition: "vars.missing", errExpected: true},
+		"invalid-compare":   {condition: "vars.list < 3", errExpected: true},
+		"unterminated":      {condition: "vars.env == 'prod", errExpected: true},
+		"missing-operand":   {condition: "vars.env ==", errExpected: true},
+		"missing-paren":     {condition: "(vars.enabled", errExpected: true},
+		"trailing":          {condition: "vars.enabled vars.enabled", errExpected: true},
+		"defined-on-string": {condition: "'a' is defined", errExpected: true},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := EvalCondition(tc.condition, data)
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestApplyConditions(t *testing.T) {
+	data := map[string]interface{}{
+		"vars": map[string]interface{}{"logging": false, "monitoring": true},
+	}
+	config := &Config{
+		Plays: []*Play{
+			{Name: "logging", When: "vars.logging", Charts: []*Chart{{Name: "loki"}}},
+			{
+				Name: "monitoring",
+				Charts: []*Chart{
+					{Name: "crds", When: "not vars.monitoring"},
+					{Name: "prometheus", When: "vars.monitoring", DependsOn: []string{"crds"}},
+				},
+			},
+		},
+	}
+
+	skipped, err := config.ApplyConditions(data)
+	assert.NilError(t, err)
+
+	assert.Equal(t, 2, len(skipped))
+	assert.Equal(t, "logging", skipped[0].Play.Name)
+	assert.Assert(t, skipped[0].Chart == nil)
+	assert.Equal(t, "monitoring", skipped[1].Play.Name)
+	assert.Equal(t, "crds", skipped[1].Chart.Name)
+
+	assert.Equal(t, 1, len(config.Plays))
+	assert.Equal(t, 1, len(config.Plays[0].Charts))
+	assert.Equal(t, "prometheus", config.Plays[0].Charts[0].Name)
+	// dependencies on skipped charts are dropped
+	assert.Equal(t, 0, len(config.Plays[0].Charts[0].DependsOn))
+	assert.NilError(t, config.CheckDependencies())
+
+	config = &Config{Plays: []*Play{{Name: "invalid", When: "vars.missing"}}}
+	_, err = config.ApplyConditions(data)
+	assert.Assert(t, err != nil)
+}
+
+func TestConditionUnmarshal(t *testing.T) {
+	var play Play
+	assert.NilError(t, yaml.Unmarshal([]byte("when: true"), &play))
+	assert.Equal(t, Condition("true"), play.When)
+
+	assert.NilError(t, yaml.Unmarshal([]byte("when: vars.enabled == 'yes'"), &play))
+	assert.Equal(t, Condition("vars.enabled == 'yes'"), play.When)
+
+	assert.Assert(t, yaml.Unmarshal([]byte("when: [a]"), &play) != nil)
+
+	// booleans from spruce operators
+	data := map[string]interface{}{
+		"plays": []interface{}{map[string]interface{}{"name": "play", "when": false}},
+	}
+	config, err := NewConfigFromMap(&data)
+	assert.NilError(t, err)
+	assert.Equal(t, Condition("false"), config.Plays[0].When)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package playbook
+
+import (
+	"github.com/bedag/kusible/pkg/target"
+)
+
+// MetadataKey is the reserved key holding the metadata of the inventory
+// entry during the evaluation of a playbook. It is removed from the
+// evaluated playbook.
+const MetadataKey = "kusible"
+
+/*
+metadata returns the metadata of the inventory entry of the given target
+made available in MetadataKey:
+
+ * entry: name of the inventory entry
+ * groups: groups of the inventory entry
+ * labels: labels of the inventory entry
+ * loader: type of the kubeconfig loader
+ * server: server url of the kubeconfig (only if the kubeconfig was loaded)
+ * version: kusible version
+ * plays: names of the plays applicable to the inventory entry
+*/
+func metadata(target *target.Target, playbookMap map[string]interface{}, version string) map[string]interface{} {
+	entry := target.Entry()
+
+	groups := []interface{}{}
+	for _, group := range entry.Groups() {
+		groups = append(groups, group)
+	}
+
+	labels := map[string]interface{}{}
+	for key, value := range entry.Labels() {
+		labels[key] = value
+	}
+
+	loader := ""
+	server := ""
+	if kubeconfig := entry.Kubeconfig(); kubeconfig != nil {
+		if kubeconfig.Loader() != nil {
+			loader = kubeconfig.Loader().Type()
+		}
+		server = kubeconfig.Server()
+	}
+
+	plays := []interface{}{}
+	if p, ok := playbookMap["plays"].([]interface{}); ok {
+		for _, play := range p {
+			if m, ok := play.(map[string]interface{}); ok {
+				if name, ok := m["name"]; ok {
+					plays = append(plays, name)
+				}
+			}
+		}
+	}
+
+	return map[string]interface{}{
+		"entry":   entry.Name(),
+		"groups":  groups,
+		"labels":  labels,
+		"loader":  loader,
+		"server":  server,
+		"version": version,
+		"plays":   plays,
+	}
+}
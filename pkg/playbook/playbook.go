@@ -19,18 +19,24 @@ package playbook
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 
 	"github.com/bedag/kusible/internal/third_party/deepcopy"
 	"github.com/bedag/kusible/internal/wrapper/spruce"
 	"github.com/bedag/kusible/pkg/playbook/config"
 	"github.com/bedag/kusible/pkg/target"
+	"github.com/bedag/kusible/pkg/values"
 	"github.com/imdario/mergo"
 	"sigs.k8s.io/yaml"
 )
 
 // New creates a Playbook for one specific target. For a given BaseConfig, each target
 // as an individual list of plays, based on the groups of the target and the plays.
-func New(baseConfig *config.BaseConfig, target *target.Target, skipEval bool, skipClusterInv bool) (*Playbook, error) {
+// The options control which data sources are merged with the playbook and if the
+// result is evaluated.
+func New(baseConfig *config.BaseConfig, target *target.Target, options Options) (*Playbook, error) {
 	// Based on the groups of the target and the groups of each play,
 	// generate a new base config containing only the plays relevant
 	// for the current target. As we have to merge the result with
@@ -42,9 +48,10 @@ func New(baseConfig *config.BaseConfig, target *target.Target, skipEval bool, sk
 	}
 
 	var mergeResult map[string]interface{}
+	var clusterInventory *map[string]interface{}
 
-	if !skipClusterInv {
-		clusterInventory, err := target.Entry().ClusterInventory()
+	if !options.SkipClusterInv {
+		clusterInventory, err = target.Entry().ClusterInventory()
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve cluster-inventory: %s", err)
 		}
@@ -55,13 +62,30 @@ func New(baseConfig *config.BaseConfig, target *target.Target, skipEval bool, sk
 		}
 	}
 
+	if options.GatherFacts {
+		facts, err := target.Entry().Facts()
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve facts: %s", err)
+		}
+
+		factsCopy, err := deepcopy.Map(*facts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy facts: %s", err)
+		}
+
+		err = mergo.Merge(&mergeResult, map[string]interface{}{"facts": factsCopy}, mergo.WithOverride)
+		if err != nil {
+			return nil, fmt.Errorf("failed merge facts and cluster-inventory: %s", err)
+		}
+	}
+
 	err = mergo.Merge(&mergeResult, playbookMap, mergo.WithOverride)
 	if err != nil {
 		return nil, fmt.Errorf("failed merge cluster-inventory and playbook: %s", err)
 	}
 
-	values := target.Values().Map()
-	err = mergo.Merge(&mergeResult, values, mergo.WithOverride)
+	targetValues := target.Values().Map()
+	err = mergo.Merge(&mergeResult, targetValues, mergo.WithOverride)
 	if err != nil {
 		return nil, fmt.Errorf("failed merge values and playbook: %s", err)
 	}
@@ -69,12 +93,27 @@ func New(baseConfig *config.BaseConfig, target *target.Target, skipEval bool, sk
 	result := &Playbook{
 		Raw: mergeResult,
 	}
-	if !skipEval {
-		err = spruce.Eval(&mergeResult, false, []string{})
+	if !options.SkipEval {
+		// the metadata is only available during the evaluation. It is merged
+		// last as it is reserved and must not be overridden by any other
+		// data source
+		meta := metadata(target, *playbookMap, options.Version)
+		evalData := make(map[string]interface{}, len(mergeResult)+1)
+		for key, value := range mergeResult {
+			evalData[key] = value
+		}
+		evalData[MetadataKey] = meta
+
+		err = spruce.EvalWithContext(&evalData, false, []string{MetadataKey}, target.Context(clusterInventory))
 		if err != nil {
-			// TODO: add optional way to dump the unevaluated yaml here
-			//doc, _ := yaml.Marshal(mergeResult)
-			//fmt.Printf("%s\n", string(doc))
+			err = values.AnnotateEvalError(target.Values(), err)
+			if options.DumpDir != "" {
+				dump, dumpErr := dumpRaw(options.DumpDir, target.Entry().Name(), result.Raw)
+				if dumpErr != nil {
+					return nil, fmt.Errorf("failed evaluate playbook config: %s (failed to dump unevaluated playbook: %s)", err, dumpErr)
+				}
+				return nil, fmt.Errorf("failed evaluate playbook config (unevaluated playbook written to %s): %s", dump, err)
+			}
 			return nil, fmt.Errorf("failed evaluate playbook config: %s", err)
 		}
 
@@ -84,16 +123,63 @@ func New(baseConfig *config.BaseConfig, target *target.Target, skipEval bool, sk
 		//       the playbook but part of the values or cluster config map
 		//       and therefore if was not tested if the play should actually be
 		//       executed for the given inventory entry
-		targetConfig, err := config.NewConfigFromMap(&mergeResult)
+		targetConfig, err := config.NewConfigFromMap(&evalData)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create playbook config: %s", err)
 		}
+		err = targetConfig.CheckDependencies()
+		if err != nil {
+			return nil, fmt.Errorf("invalid playbook dependencies: %s", err)
+		}
+
+		if !options.IgnoreConditions {
+			// the when conditions have access to the evaluated data and the
+			// metadata (pruned by the evaluation)
+			conditionData := make(map[string]interface{}, len(evalData)+1)
+			for key, value := range evalData {
+				conditionData[key] = value
+			}
+			conditionData[MetadataKey] = meta
+			result.Skipped, err = targetConfig.ApplyConditions(conditionData)
+			if err != nil {
+				return nil, err
+			}
+		}
 		result.Config = targetConfig
 	}
 
 	return result, nil
 }
 
+// dumpRaw writes the given unevaluated playbook of an inventory entry
+// to <dir>/<entry>.yml and returns the path of the written file
+func dumpRaw(dir string, entry string, raw map[string]interface{}) (string, error) {
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return "", err
+	}
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, entry+".yml")
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// SkippedCharts returns all charts skipped because their when condition
+// or the when condition of their play is false
+func (p *Playbook) SkippedCharts() []*config.Chart {
+	result := []*config.Chart{}
+	for _, skipped := range p.Skipped {
+		if skipped.Chart != nil {
+			result = append(result, skipped.Chart)
+			continue
+		}
+		result = append(result, skipped.Play.Charts...)
+	}
+	return result
+}
+
 func (p *Playbook) YAML(raw bool) ([]byte, error) {
 	// we want the raw, unevaluated config
 	if raw {
@@ -17,16 +17,21 @@ limitations under the License.
 package playbook
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/bedag/kusible/pkg/inventory"
 	invconfig "github.com/bedag/kusible/pkg/inventory/config"
 	"github.com/bedag/kusible/pkg/target"
+	"github.com/bedag/kusible/pkg/values"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
 )
 
 func TestConvert(t *testing.T) {
@@ -67,7 +72,7 @@ func TestConvert(t *testing.T) {
 			inv, err := inventory.NewInventory(invPath, ejsonSettings, true, invconfig.ClusterInventory{})
 			assert.NilError(t, err)
 
-			targets, err := target.NewTargets(".*", []string{}, varsPath, inv, true, &ejsonSettings)
+			targets, err := target.NewTargets(".*", []string{}, varsPath, inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
 			assert.NilError(t, err)
 			// create fake clients for each target so we can simulate
 			// retrieving the cluster-inventory for each
@@ -87,7 +92,7 @@ func TestConvert(t *testing.T) {
 				tgt.Entry().Kubeconfig().SetClient(clientset)
 			}
 
-			playbookSet, err := NewSet(playbookPath, targets, tc.skipEval, tc.skipClusterInv)
+			playbookSet, err := NewSet([]string{playbookPath}, targets, Options{SkipEval: tc.skipEval, SkipClusterInv: tc.skipClusterInv})
 			assert.NilError(t, err)
 			assert.Equal(t, len(targets.Targets()), len(playbookSet))
 			for name, playbook := range playbookSet {
@@ -139,3 +144,170 @@ func TestConvert(t *testing.T) {
 		})
 	}
 }
+
+func TestGatherFacts(t *testing.T) {
+	invPath := "testdata/simple/inventory.yml"
+	varsPath := "testdata/simple/group_vars"
+	playbookPath := "testdata/simple/playbook.yml"
+
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory(invPath, ejsonSettings, true, invconfig.ClusterInventory{})
+	assert.NilError(t, err)
+
+	targets, err := target.NewTargets(".*", []string{}, varsPath, inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
+	assert.NilError(t, err)
+	for _, tgt := range targets.Targets() {
+		clientset := fake.NewSimpleClientset(&v1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default",
+			},
+		})
+		tgt.Entry().Kubeconfig().SetClient(clientset)
+	}
+
+	options := Options{
+		SkipEval:       true,
+		SkipClusterInv: true,
+		GatherFacts:    true,
+	}
+	playbookSet, err := NewSet([]string{playbookPath}, targets, options)
+	assert.NilError(t, err)
+	for name, playbook := range playbookSet {
+		t.Run(name, func(t *testing.T) {
+			facts, ok := playbook.Raw["facts"].(map[string]interface{})
+			assert.Assert(t, ok)
+			_, ok = facts["kubernetes"]
+			assert.Assert(t, ok)
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory("testdata/metadata/inventory.yml", ejsonSettings, true, invconfig.ClusterInventory{})
+	assert.NilError(t, err)
+
+	targets, err := target.NewTargets(".*", []string{}, "testdata/metadata/group_vars", inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
+	assert.NilError(t, err)
+
+	options := Options{
+		SkipClusterInv: true,
+		Version:        "1.2.3",
+	}
+	playbookSet, err := NewSet([]string{"testdata/metadata/playbook.yml"}, targets, options)
+	assert.NilError(t, err)
+
+	playbook := playbookSet["testentry01"]
+	assert.Assert(t, playbook != nil)
+
+	expected := map[string]interface{}{
+		"entry":   "testentry01",
+		"groups":  []interface{}{"all", "test01", "testentry01"},
+		"labels":  map[string]interface{}{"stage": "prod"},
+		"loader":  "file",
+		"server":  "",
+		"version": "1.2.3",
+		"plays":   []interface{}{"test01"},
+	}
+	chart := playbook.Config.Plays[0].Charts[0]
+	assert.DeepEqual(t, expected, chart.Values["metadata"])
+	assert.Equal(t, "testentry01-prod", chart.Namespace)
+	assert.Equal(t, "1.2.3", chart.Version)
+
+	// the raw playbook only contains the (overridden) values
+	assert.DeepEqual(t, map[string]interface{}{"entry": "overridden"}, playbook.Raw[MetadataKey])
+
+	result, err := playbook.Map(false)
+	assert.NilError(t, err)
+	_, ok := result[MetadataKey]
+	assert.Assert(t, !ok)
+}
+
+func TestDumpOnError(t *testing.T) {
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory("testdata/dump/inventory.yml", ejsonSettings, true, invconfig.ClusterInventory{})
+	assert.NilError(t, err)
+
+	targets, err := target.NewTargets(".*", []string{}, "testdata/dump/group_vars", inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
+	assert.NilError(t, err)
+
+	dir, err := ioutil.TempDir("", "kusible-dump")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	options := Options{
+		SkipClusterInv: true,
+		DumpDir:        dir,
+	}
+	_, err = NewSet([]string{"testdata/dump/playbook.yml"}, targets, options)
+	assert.ErrorContains(t, err, "testentry01")
+	assert.ErrorContains(t, err, "$.vars.namespace: ")
+	assert.ErrorContains(t, err, "(testdata/dump/group_vars/test01.yml:4)")
+	assert.ErrorContains(t, err, filepath.Join(dir, "testentry01.yml"))
+
+	// only the failing entry is dumped
+	_, err = os.Stat(filepath.Join(dir, "testentry02.yml"))
+	assert.Assert(t, os.IsNotExist(err))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "testentry01.yml"))
+	assert.NilError(t, err)
+	var dump map[string]interface{}
+	assert.NilError(t, yaml.Unmarshal(data, &dump))
+	assert.DeepEqual(t, map[string]interface{}{
+		"stage":     "test",
+		"namespace": "(( grab vars.missing ))",
+	}, dump["vars"])
+}
+
+func TestConditions(t *testing.T) {
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory("testdata/when/inventory.yml", ejsonSettings, true, invconfig.ClusterInventory{})
+	assert.NilError(t, err)
+
+	targets, err := target.NewTargets(".*", []string{}, "testdata/when/group_vars", inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
+	assert.NilError(t, err)
+
+	options := Options{
+		SkipClusterInv: true,
+	}
+	playbookSet, err := NewSet([]string{"testdata/when/playbook.yml"}, targets, options)
+	assert.NilError(t, err)
+
+	playbook := playbookSet["testentry01"]
+	assert.Assert(t, playbook != nil)
+
+	// the conditions are evaluated after spruce and can use the metadata
+	assert.Equal(t, 1, len(playbook.Config.Plays))
+	assert.Equal(t, "apps", playbook.Config.Plays[0].Name)
+	assert.Equal(t, 1, len(playbook.Config.Plays[0].Charts))
+	assert.Equal(t, "app", playbook.Config.Plays[0].Charts[0].Name)
+
+	assert.Equal(t, 2, len(playbook.Skipped))
+	skipped := []string{}
+	for _, chart := range playbook.SkippedCharts() {
+		skipped = append(skipped, chart.Name)
+	}
+	assert.DeepEqual(t, []string{"loki", "prometheus"}, skipped)
+
+	// uninstalling has to ignore the conditions to also remove charts
+	// installed while their condition was true
+	options.IgnoreConditions = true
+	playbookSet, err = NewSet([]string{"testdata/when/playbook.yml"}, targets, options)
+	assert.NilError(t, err)
+
+	playbook = playbookSet["testentry01"]
+	assert.Assert(t, playbook != nil)
+	assert.Equal(t, 0, len(playbook.Skipped))
+	charts := []string{}
+	for _, play := range playbook.Config.Plays {
+		for _, chart := range play.Charts {
+			charts = append(charts, chart.Name)
+		}
+	}
+	assert.DeepEqual(t, []string{"loki", "prometheus", "app"}, charts)
+	assert.DeepEqual(t, []string{"prometheus"}, playbook.Config.Plays[1].Charts[1].DependsOn)
+}
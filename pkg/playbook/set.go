@@ -18,8 +18,10 @@ package playbook
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"os"
+	"sort"
+	"strings"
 
 	"github.com/bedag/kusible/pkg/playbook/config"
 	"github.com/bedag/kusible/pkg/target"
@@ -35,22 +37,26 @@ Given a list of targets, the playbook loader
 * for each target
 	* filters the plays based on the given groups
 	* retrieves the cluster-inventory of the target (optional)
+	* gathers facts about the cluster of the target (optional)
 	* loads the values relevant for the given groups (without evaluation)
 	* merges the cluster-inventory data, the target values and the filtered playbook
 	* evaluates the result
 	* unmarshalls the merged/evaluated playbook/value map into a valid playbook config structure
 */
 
-func NewSet(path string, targets *target.Targets, skipEval bool, skipClusterInv bool) (Set, error) {
-	file, err := os.Open(path)
+// NewSet creates a playbook set from the plays of all given playbook
+// files (see config.NewBaseConfigFromFiles())
+func NewSet(paths []string, targets *target.Targets, options Options) (Set, error) {
+	baseConfig, err := config.NewBaseConfigFromFiles(paths)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-	return NewSetFromReader(bufio.NewReader(file), targets, skipEval, skipClusterInv)
+	return newSetFromBaseConfig(baseConfig, targets, options)
 }
 
-func NewSetFromReader(reader *bufio.Reader, targets *target.Targets, skipEval bool, skipClusterInv bool) (Set, error) {
+// NewSetFromReader creates a playbook set from the playbook read from
+// the given reader (see config.NewBaseConfigFromReader())
+func NewSetFromReader(reader *bufio.Reader, targets *target.Targets, options Options) (Set, error) {
 	// Get the base config of the given playbook
 	// The base config contains all playbook data but only the name and groups of
 	// each play are required and parsed. We need the groups of the plays
@@ -59,18 +65,30 @@ func NewSetFromReader(reader *bufio.Reader, targets *target.Targets, skipEval bo
 	if err != nil {
 		return nil, err
 	}
+	return newSetFromBaseConfig(baseConfig, targets, options)
+}
 
+func newSetFromBaseConfig(baseConfig *config.BaseConfig, targets *target.Targets, options Options) (Set, error) {
 	result := make(Set)
+	errs := []string{}
 
+	// compile the playbooks of all targets even if one of them fails to
+	// report (and dump) the errors of all failing targets at once
 	for _, target := range targets.Targets() {
 
-		playbook, err := New(baseConfig, target, skipEval, skipClusterInv)
+		playbook, err := New(baseConfig, target, options)
 		if err != nil {
-			return nil, fmt.Errorf("Failed to create playbook for target '%s': '%s'", target.Entry().Name(), err)
+			errs = append(errs, fmt.Sprintf("Failed to create playbook for target '%s': '%s'", target.Entry().Name(), err))
+			continue
 		}
 
 		result[target.Entry().Name()] = playbook
 	}
 
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, errors.New(strings.Join(errs, "\n"))
+	}
+
 	return result, nil
 }
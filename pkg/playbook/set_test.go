@@ -22,6 +22,7 @@ import (
 	"github.com/bedag/kusible/pkg/inventory"
 	invconfig "github.com/bedag/kusible/pkg/inventory/config"
 	"github.com/bedag/kusible/pkg/target"
+	"github.com/bedag/kusible/pkg/values"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	"gotest.tools/assert"
 	v1 "k8s.io/api/core/v1"
@@ -84,7 +85,7 @@ func TestSet(t *testing.T) {
 			inv, err := inventory.NewInventory(tc.inventory, ejsonSettings, true, invconfig.ClusterInventory{})
 			assert.NilError(t, err)
 
-			targets, err := target.NewTargets(".*", []string{}, tc.vars, inv, true, &ejsonSettings)
+			targets, err := target.NewTargets(".*", []string{}, tc.vars, inv, target.Options{Values: values.Options{SkipEval: true, Ejson: ejsonSettings}})
 			assert.NilError(t, err)
 			// create fake clients for each target so we can simulate
 			// retrieving the cluster-inventory for each
@@ -104,7 +105,7 @@ func TestSet(t *testing.T) {
 				tgt.Entry().Kubeconfig().SetClient(clientset)
 			}
 
-			playbookSet, err := NewSet(tc.playbook, targets, tc.skipEval, tc.skipClusterInv)
+			playbookSet, err := NewSet([]string{tc.playbook}, targets, Options{SkipEval: tc.skipEval, SkipClusterInv: tc.skipClusterInv})
 			assert.NilError(t, err)
 			assert.Equal(t, len(targets.Targets()), len(playbookSet))
 			for name, playbook := range playbookSet {
@@ -21,6 +21,29 @@ import config "github.com/bedag/kusible/pkg/playbook/config"
 type Playbook struct {
 	Config *config.Config
 	Raw    map[string]interface{}
+	// Skipped holds the plays and charts removed from the config
+	// because their when condition is false
+	Skipped []*config.Skipped
 }
 
 type Set map[string]*Playbook
+
+// Options controls how a playbook is compiled for a target
+type Options struct {
+	// SkipEval skips the spruce evaluation of the playbook
+	SkipEval bool
+	// SkipClusterInv skips retrieving the cluster-inventory of the target
+	SkipClusterInv bool
+	// GatherFacts gathers facts about the cluster of the target and makes
+	// them available in the "facts" key of the playbook
+	GatherFacts bool
+	// Version is the kusible version made available in the
+	// metadata of the playbook (see MetadataKey)
+	Version string
+	// DumpDir is a directory the merged, unevaluated playbook of each
+	// entry failing the spruce evaluation is written to (<entry>.yml)
+	DumpDir string
+	// IgnoreConditions keeps all plays and charts of the playbook, even
+	// if their when condition is false (e.g. to uninstall all of them)
+	IgnoreConditions bool
+}
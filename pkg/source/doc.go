@@ -0,0 +1,29 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package source resolves the sources of inventories, group vars and
+playbooks, which can either be local paths or remote sources fetched
+with the loader backends (s3, http) or git.
+
+Remote sources are fetched into a cache directory and the files
+are made read-only, so they are not modified by accident. Sources
+pinned to a specific version are only fetched once, all other
+sources are fetched again for every Resolver.
+
+Sources that are tar archives (*.tar, *.tar.gz, *.tgz) are extracted.
+*/
+package source
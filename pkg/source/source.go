@@ -0,0 +1,366 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bedag/kusible/internal/archive"
+	"github.com/bedag/kusible/pkg/loader"
+	"github.com/bedag/kusible/pkg/wrapper/git"
+)
+
+// archiveExt are the extensions of sources that are extracted
+var archiveExt = [...]string{".tar.gz", ".tgz", ".tar"}
+
+// NewResolver creates a resolver caching remote sources in the given
+// directory (a kusible directory in the users cache directory if empty)
+func NewResolver(cacheDir string) (*Resolver, error) {
+	if cacheDir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDir = filepath.Join(userCacheDir, "kusible", "sources")
+	}
+	return &Resolver{
+		cacheDir: cacheDir,
+		resolved: map[string]string{},
+	}, nil
+}
+
+// IsRemote returns true if the given source is not a local path
+func IsRemote(raw string) bool {
+	src, err := Parse(raw)
+	return err != nil || src.Type != TypeLocal
+}
+
+// Parse parses the given source, see the Type* constants
+// for the supported formats
+func Parse(raw string) (*Source, error) {
+	switch {
+	case strings.HasPrefix(raw, "git::"):
+		return parseGit(strings.TrimPrefix(raw, "git::"))
+	case strings.HasPrefix(raw, "s3://"):
+		return parseS3(raw)
+	case strings.HasPrefix(raw, "https://"), strings.HasPrefix(raw, "http://"):
+		return parseHTTP(raw)
+	}
+	return &Source{Type: TypeLocal, URL: raw}, nil
+}
+
+func parseGit(raw string) (*Source, error) {
+	result := &Source{Type: TypeGit}
+
+	if i := strings.LastIndex(raw, "?"); i >= 0 {
+		query, err := url.ParseQuery(raw[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid git source %s: %s", raw, err)
+		}
+		result.Version = query.Get("ref")
+		raw = raw[:i]
+	}
+
+	// the path inside the repository is separated by a double slash
+	// (the one of the url scheme excluded)
+	start := 0
+	if i := strings.Index(raw, "://"); i >= 0 {
+		start = i + len("://")
+	}
+	if i := strings.Index(raw[start:], "//"); i >= 0 {
+		result.Path = strings.Trim(raw[start+i+2:], "/")
+		raw = raw[:start+i]
+	}
+
+	if raw == "" {
+		return nil, fmt.Errorf("invalid git source: missing repository url")
+	}
+	result.URL = raw
+	return result, nil
+}
+
+func parseS3(raw string) (*Source, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid s3 source %s: %s", raw, err)
+	}
+	result := &Source{
+		Type:    TypeS3,
+		Bucket:  u.Host,
+		Path:    strings.TrimPrefix(u.Path, "/"),
+		Version: u.Query().Get("version"),
+	}
+	if result.Bucket == "" || result.Path == "" {
+		return nil, fmt.Errorf("invalid s3 source %s: expected s3://<bucket>/<key>", raw)
+	}
+	u.RawQuery = ""
+	result.URL = u.String()
+	return result, nil
+}
+
+func parseHTTP(raw string) (*Source, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http source %s: %s", raw, err)
+	}
+	result := &Source{Type: TypeHTTP}
+
+	query := u.Query()
+	if checksum := query.Get("checksum"); checksum != "" {
+		if !strings.HasPrefix(checksum, "sha256:") {
+			return nil, fmt.Errorf("invalid http source %s: only sha256:<checksum> checksums are supported", raw)
+		}
+		result.Version = strings.ToLower(strings.TrimPrefix(checksum, "sha256:"))
+		query.Del("checksum")
+		u.RawQuery = query.Encode()
+	}
+	result.URL = u.String()
+	return result, nil
+}
+
+// name returns the name of the file or directory the source is stored as
+func (s *Source) name() string {
+	var name string
+	switch s.Type {
+	case TypeGit:
+		name = path.Base(s.Path)
+		if s.Path == "" {
+			name = strings.TrimSuffix(path.Base(s.URL), ".git")
+		}
+	case TypeS3:
+		name = path.Base(s.Path)
+	case TypeHTTP:
+		if u, err := url.Parse(s.URL); err == nil {
+			name = path.Base(u.Path)
+		}
+	}
+	if name == "" || name == "." || name == "/" {
+		name = "source"
+	}
+	return name
+}
+
+/*
+Resolve returns a local path for the given source. Local paths
+are returned as is, remote sources are fetched into the cache
+directory. Each source is fetched at most once per Resolver.
+*/
+func (r *Resolver) Resolve(raw string) (string, error) {
+	src, err := Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if src.Type == TypeLocal {
+		return raw, nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if result, ok := r.resolved[raw]; ok {
+		return result, nil
+	}
+
+	pinned, err := isPinned(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %s", raw, err)
+	}
+
+	entry := filepath.Join(r.cacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(raw))))
+	result, ok := cached(entry)
+	if !ok || !pinned {
+		result, err = r.fetch(src, entry)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch %s: %s", raw, err)
+		}
+	}
+
+	r.resolved[raw] = result
+	return result, nil
+}
+
+// isPinned returns true if the given source always refers to the same
+// data and can be read from the cache. Git refs are only pinned if they
+// are tags or commits, branches are fetched again.
+func isPinned(src *Source) (bool, error) {
+	if src.Version == "" {
+		return false, nil
+	}
+	if src.Type == TypeGit {
+		return git.IsImmutable(src.URL, src.Version)
+	}
+	return true, nil
+}
+
+// cached returns the path of the source stored in the given cache entry
+func cached(entry string) (string, bool) {
+	files, err := ioutil.ReadDir(entry)
+	if err != nil || len(files) != 1 {
+		return "", false
+	}
+	return filepath.Join(entry, files[0].Name()), true
+}
+
+// fetch fetches the given source into the given cache entry (replacing it)
+// and returns the path of the fetched file or directory
+func (r *Resolver) fetch(src *Source, entry string) (string, error) {
+	err := os.MkdirAll(r.cacheDir, 0755)
+	if err != nil {
+		return "", err
+	}
+	tmp, err := ioutil.TempDir(r.cacheDir, ".fetch-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	switch src.Type {
+	case TypeGit:
+		err = r.fetchGit(src, tmp)
+	case TypeS3:
+		err = r.fetchS3(src, tmp)
+	case TypeHTTP:
+		err = r.fetchHTTP(src, tmp)
+	default:
+		err = fmt.Errorf("unknown source type %s", src.Type)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	err = readOnly(tmp)
+	if err != nil {
+		return "", err
+	}
+	err = os.RemoveAll(entry)
+	if err != nil {
+		return "", err
+	}
+	err = os.Rename(tmp, entry)
+	if err != nil {
+		return "", err
+	}
+
+	result, ok := cached(entry)
+	if !ok {
+		return "", fmt.Errorf("source is empty")
+	}
+	return result, nil
+}
+
+func (r *Resolver) fetchGit(src *Source, dir string) error {
+	repo := filepath.Join(dir, ".repo")
+	err := git.Fetch(src.URL, src.Version, src.Path, repo)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(filepath.Join(repo, filepath.FromSlash(src.Path)), filepath.Join(dir, src.name()))
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(repo)
+}
+
+func (r *Resolver) fetchS3(src *Source, dir string) error {
+	params := map[string]interface{}{
+		"bucket":  src.Bucket,
+		"path":    src.Path,
+		"version": src.Version,
+	}
+	backend, err := loader.NewS3BackendFromParams(params)
+	if err != nil {
+		return err
+	}
+	if r.S3Downloader != nil {
+		backend.Downloader = r.S3Downloader
+	}
+
+	data, err := backend.Fetch()
+	if err != nil {
+		return err
+	}
+	return store(dir, src.name(), data)
+}
+
+func (r *Resolver) fetchHTTP(src *Source, dir string) error {
+	backend, err := loader.NewHTTPBackendFromParams(map[string]interface{}{"url": src.URL})
+	if err != nil {
+		return err
+	}
+	if r.HTTPClient != nil {
+		backend.Client = r.HTTPClient
+	}
+
+	data, err := backend.Fetch()
+	if err != nil {
+		return err
+	}
+
+	if src.Version != "" {
+		checksum := sha256.Sum256(data)
+		if hex.EncodeToString(checksum[:]) != src.Version {
+			return fmt.Errorf("checksum mismatch: expected sha256:%s, got sha256:%x", src.Version, checksum)
+		}
+	}
+	return store(dir, src.name(), data)
+}
+
+// store writes the given data to dir/name. Archives are extracted to
+// dir/name (without the archive extension) instead.
+func store(dir string, name string, data []byte) error {
+	for _, ext := range archiveExt {
+		if !strings.HasSuffix(name, ext) {
+			continue
+		}
+
+		var r io.Reader = bytes.NewReader(data)
+		if ext != ".tar" {
+			gz, err := gzip.NewReader(r)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			r = gz
+		}
+		return archive.Untar(r, filepath.Join(dir, strings.TrimSuffix(name, ext)))
+	}
+	return ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+}
+
+// readOnly removes the write permissions of all files below dir
+func readOnly(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			return os.Chmod(path, 0444)
+		}
+		return nil
+	})
+}
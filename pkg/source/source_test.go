@@ -0,0 +1,296 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+	"gotest.tools/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]struct {
+		raw      string
+		err      bool
+		expected Source
+	}{
+		"local": {
+			raw:      "group_vars",
+			expected: Source{Type: TypeLocal, URL: "group_vars"},
+		},
+		"git": {
+			raw:      "git::https://example.com/org/repo.git//deploy/group_vars?ref=v1.0.0",
+			expected: Source{Type: TypeGit, URL: "https://example.com/org/repo.git", Path: "deploy/group_vars", Version: "v1.0.0"},
+		},
+		"git-ssh": {
+			raw:      "git::git@example.com:org/repo.git//playbook.yml",
+			expected: Source{Type: TypeGit, URL: "git@example.com:org/repo.git", Path: "playbook.yml"},
+		},
+		"git-repo": {
+			raw:      "git::https://example.com/org/repo.git",
+			expected: Source{Type: TypeGit, URL: "https://example.com/org/repo.git"},
+		},
+		"s3": {
+			raw:      "s3://bucket/kusible/group_vars.tar.gz?version=abc",
+			expected: Source{Type: TypeS3, URL: "s3://bucket/kusible/group_vars.tar.gz", Bucket: "bucket", Path: "kusible/group_vars.tar.gz", Version: "abc"},
+		},
+		"s3-missing-key": {
+			raw: "s3://bucket",
+			err: true,
+		},
+		"https": {
+			raw:      "https://example.com/playbook.yml?token=x&checksum=sha256:ABC",
+			expected: Source{Type: TypeHTTP, URL: "https://example.com/playbook.yml?token=x", Version: "abc"},
+		},
+		"https-md5": {
+			raw: "https://example.com/playbook.yml?checksum=md5:abc",
+			err: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			result, err := Parse(tt.raw)
+			if tt.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.expected, *result)
+		})
+	}
+}
+
+func TestResolveHTTP(t *testing.T) {
+	playbook := []byte("plays: []\n")
+	archive := testArchive(t, map[string]string{"all.yml": "vars: {a: 1}\n"})
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/playbook.yml":
+			w.Write(playbook)
+		case "/group_vars.tar.gz":
+			w.Write(archive)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	cacheDir, err := ioutil.TempDir("", "kusible-sources-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	checksum := fmt.Sprintf("%x", sha256.Sum256(playbook))
+	pinned := server.URL + "/playbook.yml?checksum=sha256:" + checksum
+
+	// pinned sources are only fetched once, even by different resolvers
+	for i := 0; i < 2; i++ {
+		resolver, err := NewResolver(cacheDir)
+		assert.NilError(t, err)
+		path, err := resolver.Resolve(pinned)
+		assert.NilError(t, err)
+		assert.Equal(t, "playbook.yml", filepath.Base(path))
+		data, err := ioutil.ReadFile(path)
+		assert.NilError(t, err)
+		assert.Equal(t, string(playbook), string(data))
+	}
+	assert.Equal(t, 1, requests)
+
+	resolver, err := NewResolver(cacheDir)
+	assert.NilError(t, err)
+
+	// files are read-only
+	path, err := resolver.Resolve(server.URL + "/playbook.yml")
+	assert.NilError(t, err)
+	info, err := os.Stat(path)
+	assert.NilError(t, err)
+	assert.Equal(t, os.FileMode(0444), info.Mode().Perm())
+
+	// archives are extracted
+	path, err = resolver.Resolve(server.URL + "/group_vars.tar.gz")
+	assert.NilError(t, err)
+	data, err := ioutil.ReadFile(filepath.Join(path, "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {a: 1}\n", string(data))
+
+	_, err = resolver.Resolve(server.URL + "/playbook.yml?checksum=sha256:0000")
+	assert.ErrorContains(t, err, "checksum mismatch")
+
+	_, err = resolver.Resolve(server.URL + "/missing.yml")
+	assert.ErrorContains(t, err, "404")
+
+	// local paths are returned as is
+	path, err = resolver.Resolve("group_vars")
+	assert.NilError(t, err)
+	assert.Equal(t, "group_vars", path)
+}
+
+func TestResolveGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git executable not available")
+	}
+
+	repo, err := ioutil.TempDir("", "kusible-source-repo-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(repo)
+	assert.NilError(t, os.MkdirAll(filepath.Join(repo, "group_vars"), 0755))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(repo, "group_vars", "all.yml"), []byte("vars: {a: 1}\n"), 0644))
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(repo, "playbook.yml"), []byte("plays: []\n"), 0644))
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+		{"tag", "v1"},
+		{"branch", "stable"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		assert.NilError(t, cmd.Run())
+	}
+
+	cacheDir, err := ioutil.TempDir("", "kusible-sources-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(cacheDir)
+	resolver, err := NewResolver(cacheDir)
+	assert.NilError(t, err)
+
+	path, err := resolver.Resolve("git::" + repo + "//group_vars?ref=v1")
+	assert.NilError(t, err)
+	assert.Equal(t, "group_vars", filepath.Base(path))
+	data, err := ioutil.ReadFile(filepath.Join(path, "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {a: 1}\n", string(data))
+
+	path, err = resolver.Resolve("git::" + repo + "//playbook.yml")
+	assert.NilError(t, err)
+	data, err = ioutil.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, "plays: []\n", string(data))
+
+	// branches are fetched again (by a new resolver using the same
+	// cache), tags are read from the cache
+	branchSource := "git::" + repo + "//group_vars?ref=stable"
+	path, err = resolver.Resolve(branchSource)
+	assert.NilError(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(path, "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {a: 1}\n", string(data))
+
+	assert.NilError(t, ioutil.WriteFile(filepath.Join(repo, "group_vars", "all.yml"), []byte("vars: {a: 2}\n"), 0644))
+	for _, args := range [][]string{
+		{"checkout", "-q", "stable"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-a", "-m", "update"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		assert.NilError(t, cmd.Run())
+	}
+
+	resolver, err = NewResolver(cacheDir)
+	assert.NilError(t, err)
+	path, err = resolver.Resolve(branchSource)
+	assert.NilError(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(path, "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {a: 2}\n", string(data))
+
+	path, err = resolver.Resolve("git::" + repo + "//group_vars?ref=v1")
+	assert.NilError(t, err)
+	data, err = ioutil.ReadFile(filepath.Join(path, "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {a: 1}\n", string(data))
+}
+
+type mockedS3DownloadManager struct {
+	s3manageriface.DownloaderAPI
+	objects map[string][]byte
+}
+
+func (d mockedS3DownloadManager) Download(w io.WriterAt, input *s3.GetObjectInput, options ...func(*s3manager.Downloader)) (int64, error) {
+	key := fmt.Sprintf("%s/%s@%s", aws.StringValue(input.Bucket), aws.StringValue(input.Key), aws.StringValue(input.VersionId))
+	content, ok := d.objects[key]
+	if !ok {
+		return 0, fmt.Errorf("object %s not found", key)
+	}
+	count, err := w.WriteAt(content, 0)
+	return int64(count), err
+}
+
+func TestResolveS3(t *testing.T) {
+	for key, value := range map[string]string{"S3_ACCESSKEY": "foo", "S3_SECRETKEY": "bar", "S3_SERVER": "baz"} {
+		assert.NilError(t, os.Setenv(key, value))
+		defer os.Unsetenv(key)
+	}
+
+	cacheDir, err := ioutil.TempDir("", "kusible-sources-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(cacheDir)
+	resolver, err := NewResolver(cacheDir)
+	assert.NilError(t, err)
+	resolver.S3Downloader = mockedS3DownloadManager{
+		objects: map[string][]byte{
+			"bucket/inventory.yml@":   []byte("latest"),
+			"bucket/inventory.yml@v1": []byte("v1"),
+		},
+	}
+
+	for version, expected := range map[string]string{"": "latest", "v1": "v1"} {
+		raw := "s3://bucket/inventory.yml"
+		if version != "" {
+			raw = raw + "?version=" + version
+		}
+		path, err := resolver.Resolve(raw)
+		assert.NilError(t, err)
+		data, err := ioutil.ReadFile(path)
+		assert.NilError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+}
+
+// testArchive creates a tar.gz archive containing the given files
+func testArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	archive := tar.NewWriter(gz)
+	for name, content := range files {
+		assert.NilError(t, archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := archive.Write([]byte(content))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, archive.Close())
+	assert.NilError(t, gz.Close())
+	return buf.Bytes()
+}
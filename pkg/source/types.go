@@ -0,0 +1,65 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package source
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/service/s3/s3manager/s3manageriface"
+)
+
+const (
+	// TypeLocal sources are local paths
+	TypeLocal = "local"
+	// TypeS3 sources are s3://<bucket>/<key>[?version=<version id>]
+	TypeS3 = "s3"
+	// TypeGit sources are git::<repository url>[//<path>][?ref=<ref>]
+	TypeGit = "git"
+	// TypeHTTP sources are http(s)://<url>[?checksum=sha256:<checksum>]
+	TypeHTTP = "http"
+)
+
+// Source is a parsed (remote) source
+type Source struct {
+	// Type is one of the Type* constants
+	Type string
+	// URL is the url of the source without the kusible specific
+	// parameters (the repository url for git sources)
+	URL string
+	// Bucket is the bucket of s3 sources
+	Bucket string
+	// Path is the key of s3 sources or the path inside the repository
+	// of git sources
+	Path string
+	// Version pins the source: the object version id (s3), the ref (git)
+	// or the sha256 checksum (http). Pinned sources are cached (git refs
+	// only if they are tags or commits).
+	Version string
+}
+
+// Resolver fetches remote sources into a local cache directory.
+// It is safe for concurrent use.
+type Resolver struct {
+	cacheDir string
+	mutex    sync.Mutex
+	resolved map[string]string
+	// HTTPClient is used to fetch http sources if it is not nil
+	HTTPClient *http.Client
+	// S3Downloader is used to fetch s3 sources if it is not nil
+	S3Downloader s3manageriface.DownloaderAPI
+}
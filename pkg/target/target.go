@@ -19,17 +19,31 @@ package target
 import (
 	"fmt"
 
+	"github.com/bedag/kusible/internal/wrapper/spruce"
 	inv "github.com/bedag/kusible/pkg/inventory"
 	"github.com/bedag/kusible/pkg/values"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
 )
 
-func New(entry *inv.Entry, valuesPath string, skipEval bool, ejson *ejson.Settings) (*Target, error) {
+// New creates a target for the given inventory entry. The host used to
+// look up host vars is always the name of the entry. If a host vars
+// directory is given, the entry specific values are only read from
+// the host vars directory and not from the implicit group named like
+// the entry (see inventory.Entry.ConfiguredGroups()).
+func New(entry *inv.Entry, valuesPath string, options Options) (*Target, error) {
+	groups := entry.Groups()
+	if options.Values.HostVarsDir != "" {
+		groups = entry.ConfiguredGroups()
+	}
+
 	target := &Target{
-		entry: entry,
+		entry:  entry,
+		groups: groups,
+		ejson:  options.Values.Ejson,
 	}
-	groups := entry.Groups()
-	values, err := values.New(valuesPath, groups, skipEval, *ejson)
+	valuesOptions := options.Values
+	valuesOptions.Host = entry.Name()
+	values, err := values.New(valuesPath, groups, valuesOptions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compile values for target '%s': %s", entry.Name(), err)
 	}
@@ -44,3 +58,33 @@ func (t *Target) Values() values.Values {
 func (t *Target) Entry() *inv.Entry {
 	return t.entry
 }
+
+// Groups returns the groups used to compile the values of the target
+func (t *Target) Groups() []string {
+	return t.groups
+}
+
+// EJSON returns the ejson settings used to compile the values of the target
+func (t *Target) EJSON() *ejson.Settings {
+	return &t.ejson
+}
+
+// Context returns the context of the kusible spruce operators for the target.
+// clusterInventory is the cluster inventory as returned by the inventory entry
+// (may be nil if it was not retrieved).
+func (t *Target) Context(clusterInventory *map[string]interface{}) *spruce.Context {
+	ctx := &spruce.Context{
+		Entry:   t.entry.Name(),
+		Groups:  t.entry.Groups(),
+		Ejson:   t.ejson,
+		BaseDir: values.BaseDir(t.values),
+	}
+	if clusterInventory != nil {
+		if vars, ok := (*clusterInventory)["vars"].(map[string]interface{}); ok {
+			ctx.ClusterInventory = vars
+		} else {
+			ctx.ClusterInventory = map[string]interface{}{}
+		}
+	}
+	return ctx
+}
@@ -21,7 +21,7 @@ import (
 
 	"github.com/bedag/kusible/pkg/inventory"
 	invconf "github.com/bedag/kusible/pkg/inventory/config"
-	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/pkg/values"
 	"gotest.tools/assert"
 )
 
@@ -63,7 +63,7 @@ func TestTarget(t *testing.T) {
 		t.Run(name, func(t *testing.T) {
 			entry, err := inventory.NewEntryFromConfig(config)
 			assert.NilError(t, err)
-			target, err := New(entry, "testdata/group_vars", tc.skipEval, &ejson.Settings{})
+			target, err := New(entry, "testdata/group_vars", Options{Values: values.Options{SkipEval: tc.skipEval}})
 			assert.NilError(t, err)
 			got := target.Values().Map()
 			assert.DeepEqual(t, tc.want, got)
@@ -71,3 +71,55 @@ func TestTarget(t *testing.T) {
 	}
 
 }
+
+func TestTargetHostVars(t *testing.T) {
+	tests := map[string]struct {
+		hostVarsDir string
+		entryGroups []string
+		groups      []string
+		want        map[string]interface{}
+	}{
+		"without-host-vars": {
+			hostVarsDir: "",
+			entryGroups: []string{},
+			groups:      []string{"all", "cluster-01"},
+			want:        map[string]interface{}{"source": "group", "all": true, "group": true},
+		},
+		"with-host-vars": {
+			hostVarsDir: "testdata/hostvars/host_vars",
+			entryGroups: []string{},
+			groups:      []string{"all"},
+			want:        map[string]interface{}{"source": "host", "all": true, "host": true},
+		},
+		"with-host-vars-and-group": {
+			// a group named like the entry that is explicitly part of the
+			// entry config is kept
+			hostVarsDir: "testdata/hostvars/host_vars",
+			entryGroups: []string{"cluster-01"},
+			groups:      []string{"all", "cluster-01"},
+			want:        map[string]interface{}{"source": "host", "all": true, "group": true, "host": true},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			config := &invconf.Entry{
+				Name:   "cluster-01",
+				Groups: tc.entryGroups,
+				Kubeconfig: invconf.Kubeconfig{
+					Backend: "s3",
+					Params:  make(invconf.Params),
+				},
+			}
+			entry, err := inventory.NewEntryFromConfig(config)
+			assert.NilError(t, err)
+			options := Options{Values: values.Options{SkipEval: true, HostVarsDir: tc.hostVarsDir}}
+			target, err := New(entry, "testdata/hostvars/group_vars", options)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.groups, target.Groups())
+			assert.DeepEqual(t, tc.want, target.Values().Map())
+			// the entry itself is still part of the group named like the entry
+			assert.DeepEqual(t, append(append([]string{"all"}, tc.entryGroups...), "cluster-01"), entry.Groups())
+		})
+	}
+}
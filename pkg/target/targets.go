@@ -17,13 +17,29 @@ limitations under the License.
 package target
 
 import (
+	"errors"
 	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	inv "github.com/bedag/kusible/pkg/inventory"
+	"github.com/bedag/kusible/pkg/values"
 )
 
-func NewTargets(filter string, limits []string, valuesPath string, inventory *inv.Inventory, skipEval bool, ejson *ejson.Settings) (*Targets, error) {
+/*
+NewTargets creates the targets for all inventory entries matched by filter
+and limits, compiling the values of at most options.Workers targets in
+parallel (runtime.NumCPU() if not set). If no cache is given in the values
+options, a cache shared by all targets is used so files common to multiple
+targets are only parsed once.
+
+If the values of multiple targets cannot be compiled, the errors of all
+failed targets are returned (ordered by entry name).
+*/
+func NewTargets(filter string, limits []string, valuesPath string, inventory *inv.Inventory, options Options) (*Targets, error) {
 	targetNames, err := inventory.EntryNames(filter, limits)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get possible entries from inventory: %s", err)
@@ -33,19 +49,59 @@ func NewTargets(filter string, limits []string, valuesPath string, inventory *in
 		limits:     limits,
 		filter:     filter,
 		valuesPath: valuesPath,
+		ejson:      &options.Values.Ejson,
 		targets:    make(map[string]*Target, len(targetNames)),
 	}
 	if len(targetNames) <= 0 {
 		return targets, nil
 	}
 
+	if options.Values.Cache == nil {
+		options.Values.Cache = values.NewCache()
+	}
+	workers := options.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	sort.Strings(targetNames)
+
+	errs := make(map[string]error)
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workers)
+
 	for _, name := range targetNames {
 		entry := inventory.Entries()[name]
-		target, err := New(entry, valuesPath, skipEval, ejson)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create target for inventory entry '%s': %s", name, err)
+		// see https://golang.org/doc/faq#closures_and_goroutines
+		name := name
+
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			target, err := New(entry, valuesPath, options)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs[name] = fmt.Errorf("failed to create target for inventory entry '%s': %s", name, err)
+				return
+			}
+			targets.targets[name] = target
+		}()
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		messages := []string{}
+		for _, name := range targetNames {
+			if err, ok := errs[name]; ok {
+				messages = append(messages, err.Error())
+			}
 		}
-		targets.targets[name] = target
+		return nil, errors.New(strings.Join(messages, "\n"))
 	}
 	return targets, nil
 }
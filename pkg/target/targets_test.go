@@ -18,10 +18,12 @@ package target
 
 import (
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/bedag/kusible/pkg/inventory"
 	invconf "github.com/bedag/kusible/pkg/inventory/config"
+	"github.com/bedag/kusible/pkg/values"
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	"gotest.tools/assert"
 )
@@ -157,7 +159,7 @@ func TestTargets(t *testing.T) {
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			targets, err := NewTargets(tc.filter, tc.limits, "testdata/group_vars", inv, tc.skipEval, &ejsonSettings)
+			targets, err := NewTargets(tc.filter, tc.limits, "testdata/group_vars", inv, Options{Values: values.Options{SkipEval: tc.skipEval, Ejson: ejsonSettings}})
 			assert.Equal(t, tc.expected.error, err != nil)
 			if !tc.expected.error {
 				gotTargets := targets.Targets()
@@ -176,3 +178,57 @@ func TestTargets(t *testing.T) {
 		})
 	}
 }
+
+func TestTargetsWithWorkers(t *testing.T) {
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory("testdata/inventory.yml", ejsonSettings, true, invconf.ClusterInventory{})
+	assert.NilError(t, err)
+
+	options := values.Options{Ejson: ejsonSettings}
+	sequential, err := NewTargets(".*", []string{}, "testdata/group_vars", inv, Options{Values: options, Workers: 1})
+	assert.NilError(t, err)
+	parallel, err := NewTargets(".*", []string{}, "testdata/group_vars", inv, Options{Values: options, Workers: 4})
+	assert.NilError(t, err)
+
+	assert.Equal(t, len(sequential.Targets()), len(parallel.Targets()))
+	for name, target := range sequential.Targets() {
+		assert.DeepEqual(t, target.Values().Map(), parallel.Targets()[name].Values().Map())
+	}
+
+	inv, err = inventory.NewInventory("testdata/broken/inventory.yml", ejsonSettings, true, invconf.ClusterInventory{})
+	assert.NilError(t, err)
+
+	_, err = NewTargets(".*", []string{}, "testdata/broken/group_vars", inv, Options{Values: options, Workers: 4})
+	assert.Assert(t, err != nil)
+	lines := strings.Split(err.Error(), "\n")
+	assert.Equal(t, 2, len(lines))
+	assert.Assert(t, strings.HasPrefix(lines[0], "failed to create target for inventory entry 'cluster-02'"))
+	assert.Assert(t, strings.HasPrefix(lines[1], "failed to create target for inventory entry 'cluster-03'"))
+}
+
+func TestTargetsConcurrentEval(t *testing.T) {
+	ejsonSettings := ejson.Settings{}
+
+	inv, err := inventory.NewInventory("testdata/concurrent/inventory.yml", ejsonSettings, true, invconf.ClusterInventory{})
+	assert.NilError(t, err)
+
+	options := values.Options{Ejson: ejsonSettings, MergeMode: values.MergeModeSpruce}
+	targets, err := NewTargets(".*", []string{}, "testdata/concurrent/group_vars", inv, Options{Values: options, Workers: 8})
+	assert.NilError(t, err)
+	assert.Equal(t, 16, len(targets.Targets()))
+
+	// the (( prune )) and (( sort )) operators of one entry must
+	// not be applied to the values of another entry
+	for name, target := range targets.Targets() {
+		expected := map[string]interface{}{
+			"entry": name,
+			"list":  []interface{}{"c", "a", "b"},
+		}
+		if target.Entry().Groups()[1] == "sort" {
+			expected["list"] = []interface{}{"a", "b", "c"}
+			expected["meta"] = map[string]interface{}{"stage": "test"}
+		}
+		assert.DeepEqual(t, expected, target.Values().Map())
+	}
+}
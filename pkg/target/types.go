@@ -22,6 +22,15 @@ import (
 	"github.com/bedag/kusible/pkg/values"
 )
 
+// Options controls how the targets are created
+type Options struct {
+	// Values controls how the values of each target are compiled
+	Values values.Options
+	// Workers is the number of targets compiled in parallel
+	// (runtime.NumCPU() if < 1)
+	Workers int
+}
+
 type Targets struct {
 	limits     []string
 	filter     string
@@ -32,5 +41,7 @@ type Targets struct {
 
 type Target struct {
 	entry  *inv.Entry
+	groups []string
 	values values.Values
+	ejson  ejson.Settings
 }
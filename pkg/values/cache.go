@@ -0,0 +1,65 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"os"
+
+	"github.com/bedag/kusible/internal/third_party/deepcopy"
+)
+
+// NewCache creates an empty values file cache
+func NewCache() *Cache {
+	return &Cache{
+		entries: map[string]*cacheEntry{},
+	}
+}
+
+/*
+load returns a copy of the cached data of the given file. If the file is not
+cached or has changed since it was cached, loadFn is used to load it. Concurrent
+loads of the same file are only executed once, failed loads are not cached.
+*/
+func (c *Cache) load(path string, loadFn func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mutex.Lock()
+	entry, ok := c.entries[path]
+	if !ok || !entry.modTime.Equal(stat.ModTime()) || entry.size != stat.Size() {
+		entry = &cacheEntry{
+			modTime: stat.ModTime(),
+			size:    stat.Size(),
+		}
+		c.entries[path] = entry
+	}
+	c.mutex.Unlock()
+
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if !entry.loaded {
+		data, err := loadFn()
+		if err != nil {
+			return nil, err
+		}
+		entry.data = data
+		entry.loaded = true
+	}
+	return deepcopy.Map(entry.data)
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kusible-cache-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "all.yml")
+	assert.NilError(t, ioutil.WriteFile(path, []byte("vars: {a: b, list: [1]}\n"), 0644))
+
+	cache := NewCache()
+	options := Options{SkipEval: true, Cache: cache}
+
+	loads := 0
+	loadFn := func() (map[string]interface{}, error) {
+		loads++
+		f := &file{path: path}
+		return f.parse()
+	}
+
+	first, err := cache.load(path, loadFn)
+	assert.NilError(t, err)
+	// modifying the returned data must not modify the cached data
+	first["vars"].(map[string]interface{})["a"] = "modified"
+	first["vars"].(map[string]interface{})["list"].([]interface{})[0] = 2
+
+	second, err := cache.load(path, loadFn)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, loads)
+	assert.DeepEqual(t, map[string]interface{}{
+		"vars": map[string]interface{}{"a": "b", "list": []interface{}{float64(1)}},
+	}, second)
+
+	// changed files are loaded again
+	assert.NilError(t, ioutil.WriteFile(path, []byte("vars: {a: changed}\n"), 0644))
+	assert.NilError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	f, err := NewFile(path, options)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, map[string]interface{}{
+		"vars": map[string]interface{}{"a": "changed"},
+	}, f.Map())
+
+	_, err = cache.load(filepath.Join(dir, "missing.yml"), loadFn)
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,53 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Diff returns the changes of all leaf values (everything but non-empty
+// maps, lists are compared as a whole) between old and new, sorted by path
+func Diff(old map[string]interface{}, new map[string]interface{}) []Change {
+	oldLeaves := map[string]interface{}{}
+	flatten(old, "", oldLeaves)
+	newLeaves := map[string]interface{}{}
+	flatten(new, "", newLeaves)
+
+	result := []Change{}
+	for path, oldValue := range oldLeaves {
+		newValue, ok := newLeaves[path]
+		if !ok {
+			result = append(result, Change{Path: path, Type: ChangeRemoved, Old: oldValue})
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			result = append(result, Change{Path: path, Type: ChangeModified, Old: oldValue, New: newValue})
+		}
+	}
+	for path, newValue := range newLeaves {
+		if _, ok := oldLeaves[path]; !ok {
+			result = append(result, Change{Path: path, Type: ChangeAdded, New: newValue})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestDiff(t *testing.T) {
+	tests := map[string]struct {
+		old      map[string]interface{}
+		new      map[string]interface{}
+		expected []Change
+	}{
+		"equal": {
+			old:      map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			new:      map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			expected: []Change{},
+		},
+		"changed": {
+			old: map[string]interface{}{"a": map[string]interface{}{"b": "c", "list": []interface{}{1, 2}}},
+			new: map[string]interface{}{"a": map[string]interface{}{"b": "d", "list": []interface{}{1}}},
+			expected: []Change{
+				{Path: "a.b", Type: ChangeModified, Old: "c", New: "d"},
+				{Path: "a.list", Type: ChangeModified, Old: []interface{}{1, 2}, New: []interface{}{1}},
+			},
+		},
+		"added and removed": {
+			old: map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			new: map[string]interface{}{"a": map[string]interface{}{"d": "e"}, "f": "g"},
+			expected: []Change{
+				{Path: "a.b", Type: ChangeRemoved, Old: "c"},
+				{Path: "a.d", Type: ChangeAdded, New: "e"},
+				{Path: "f", Type: ChangeAdded, New: "g"},
+			},
+		},
+		"map replaced by value": {
+			old: map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			new: map[string]interface{}{"a": "b"},
+			expected: []Change{
+				{Path: "a", Type: ChangeAdded, New: "b"},
+				{Path: "a.b", Type: ChangeRemoved, Old: "c"},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, Diff(tc.old, tc.new))
+		})
+	}
+}
@@ -18,22 +18,32 @@ package values
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/bedag/kusible/internal/third_party/deepcopy"
 	"github.com/bedag/kusible/internal/wrapper/spruce"
-	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	"github.com/imdario/mergo"
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/yaml"
 )
 
-func NewDirectory(path string, groups []string, skipEval bool, ejsonSettings ejson.Settings) (*directory, error) {
+// NewDirectory loads the values of the given groups from the values directory at path
+func NewDirectory(path string, groups []string, options Options) (*directory, error) {
 	result := &directory{
 		path:            path,
-		ejson:           ejsonSettings,
-		skipEval:        skipEval,
+		overlays:        options.Overlays,
+		ejson:           options.Ejson,
+		sops:            options.Sops,
+		skipEval:        options.SkipEval,
+		hostVarsDir:     options.HostVarsDir,
+		host:            options.Host,
+		mergeMode:       options.MergeMode,
+		cache:           options.Cache,
+		extraVars:       options.ExtraVars,
+		templates:       options.Templates,
 		groups:          groups,
 		orderedFileList: []string{},
 		data:            map[string]interface{}{},
@@ -55,15 +65,25 @@ Each group in the list may match either
  * *.yaml
  * *.json
  * *.ejson
+ * *.sops.yml
+ * *.sops.yaml
+ * *.sops.json
 
 in the given directory. It is not required that a group has any matching
-files / directories.
+files / directories. If a host vars directory and a host are given, the
+files / directories of the host in the host vars directory are merged
+after all groups following the same rules.
 
 The contents of the files / directories will then be merged according
 to the order of the given group list. Values of groups at the end of the
 list will override values from the end of the list (least specific to most
 specific ordering).
 
+By default, values of later files simply override values (including lists)
+of earlier files. With the spruce merge mode, the files are merged by the
+spruce merge engine, allowing the use of array operators like (( append ))
+or (( merge on name )) across files.
+
 If an entry in the given directory is itself a directory, its contents
 (including all subdirectories) will be merged in alphabetical order.
 
@@ -76,10 +96,16 @@ be merged with the following priority (least to most specific)
  * *.yaml
  * *.json
  * *.ejson
+ * *.sops.yml
+ * *.sops.yaml
+ * *.sops.json
 
 Files can make use of spruce operators (https://github.com/geofffranks/spruce/blob/master/doc/operators.md).
 *.ejson will be treated as ejson (https://github.com/Shopify/ejson) encrypted
 and decrypted before merging if a matching private key was provided.
+*.sops.* files (and all other files containing sops metadata) will be
+decrypted with sops (https://github.com/mozilla/sops) before merging,
+values templates are never decrypted.
 */
 func (d *directory) load() error {
 	if len(d.data) > 0 {
@@ -102,66 +128,129 @@ func (d *directory) load() error {
 		"files": strings.Join(d.orderedFileList[:], " "),
 	}).Debug("Ordered list of files to merge")
 
-	// merge everything while decrypting any ejson files encountered
+	// load everything while decrypting any ejson files encountered
+	docs := []map[string]interface{}{}
 	for _, path := range d.orderedFileList {
-		file, err := NewFile(path, true, d.ejson)
+		options := Options{
+			SkipEval:  true,
+			Ejson:     d.ejson,
+			Sops:      d.sops,
+			Cache:     d.cache,
+			Host:      d.host,
+			Templates: d.templates,
+		}
+		file, err := newFile(path, options, d.groups)
 		if err != nil {
 			return err
 		}
-		doc := file.Map()
-		err = mergo.Merge(&d.data, doc, mergo.WithOverride)
+		docs = append(docs, file.Map())
+		d.sources = append(d.sources, file.source)
+	}
+
+	// extra vars override the values of all files
+	if len(d.extraVars) > 0 {
+		extraVars, err := deepcopy.Map(d.extraVars)
 		if err != nil {
 			return err
 		}
+		docs = append(docs, extraVars)
+		d.sources = append(d.sources, newSource(ExtraVarsSource, extraVars))
+	}
+
+	ctx := &spruce.Context{
+		Entry:   d.host,
+		Groups:  d.groups,
+		Ejson:   d.ejson,
+		BaseDir: BaseDir(d),
 	}
 
-	err = spruce.Eval(&d.data, d.skipEval, pruneKeys)
-	return err
+	switch d.mergeMode {
+	case MergeModeSpruce:
+		err = spruce.MergeEval(&d.data, docs, d.skipEval, pruneKeys, ctx)
+	case MergeModeOverride, "":
+		for _, doc := range docs {
+			err = mergo.Merge(&d.data, doc, mergo.WithOverride)
+			if err != nil {
+				return err
+			}
+		}
+		err = spruce.EvalWithContext(&d.data, d.skipEval, pruneKeys, ctx)
+	default:
+		return fmt.Errorf("unknown merge mode: %s", d.mergeMode)
+	}
+	return AnnotateEvalError(d, err)
 }
 
 func (d *directory) createOrderedDataFileList() error {
+	// each group is resolved across the values directory and all overlays
+	paths := append([]string{d.path}, d.overlays...)
 	for _, group := range d.groups {
-		var orderedGroupFileList []string
-		groupDirectory := filepath.Join(d.path, group)
-
-		if stat, err := os.Stat(groupDirectory); err == nil && stat.Mode().IsDir() {
-			err := filepath.Walk(groupDirectory, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					log.WithFields(log.Fields{
-						"path": path,
-					}).Warn(err.Error())
-					return nil
-				}
-
-				if info.IsDir() && path != groupDirectory {
-					files, _ := DirectoryDataFiles(path, "*")
-					orderedGroupFileList = append(orderedGroupFileList, files...)
-					return nil
-				}
-
-				return nil
-			})
+		for _, path := range paths {
+			files, err := orderedDataFileList(path, group, d.templates)
 			if err != nil {
 				return err
 			}
+			d.orderedFileList = append(d.orderedFileList, files...)
 		}
-		// add all files contained in subdirectories of the group directory
-		// e.g. <directory>/<group>/**/*.{yml,yaml,json,ejson}
-		d.orderedFileList = append(d.orderedFileList, orderedGroupFileList...)
-
-		// add all files contained in the group directory
-		// e.g. <directory>/<group>/*.{yml,yaml,json,ejson}
-		files, _ := DirectoryDataFiles(groupDirectory, "*")
-		d.orderedFileList = append(d.orderedFileList, files...)
+	}
 
-		// add all group files
-		// e.g. <directory>/<group>.{yml,yaml,json,ejson}
-		files, _ = DirectoryDataFiles(d.path, group)
+	// host vars are merged after all group vars, following the same
+	// rules as a group named after the host
+	if d.hostVarsDir != "" && d.host != "" {
+		files, err := orderedDataFileList(d.hostVarsDir, d.host, d.templates)
+		if err != nil {
+			return err
+		}
 		d.orderedFileList = append(d.orderedFileList, files...)
 	}
 	return nil
 }
 
+// orderedDataFileList returns the ordered list of files belonging to
+// the given name (group or host) in the given directory
+func orderedDataFileList(path string, name string, templates bool) ([]string, error) {
+	var result []string
+	var orderedGroupFileList []string
+	groupDirectory := filepath.Join(path, name)
+
+	if stat, err := os.Stat(groupDirectory); err == nil && stat.Mode().IsDir() {
+		err := filepath.Walk(groupDirectory, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				log.WithFields(log.Fields{
+					"path": path,
+				}).Warn(err.Error())
+				return nil
+			}
+
+			if info.IsDir() && path != groupDirectory {
+				files, _ := directoryDataFiles(path, "*", templates)
+				orderedGroupFileList = append(orderedGroupFileList, files...)
+				return nil
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	// add all files contained in subdirectories of the group directory
+	// e.g. <directory>/<group>/**/*.{yml,yaml,json,ejson}
+	result = append(result, orderedGroupFileList...)
+
+	// add all files contained in the group directory
+	// e.g. <directory>/<group>/*.{yml,yaml,json,ejson}
+	files, _ := directoryDataFiles(groupDirectory, "*", templates)
+	result = append(result, files...)
+
+	// add all group files
+	// e.g. <directory>/<group>.{yml,yaml,json,ejson}
+	files, _ = directoryDataFiles(path, name, templates)
+	result = append(result, files...)
+
+	return result, nil
+}
+
 /*
 OrderedDataFileList traverses the given directory and returns a list of
 files according to the rules described for the Compile method
@@ -170,6 +259,11 @@ func (d *directory) OrderedDataFileList() []string {
 	return d.orderedFileList
 }
 
+// Files returns the files the values were merged from
+func (d *directory) Files() []string {
+	return d.orderedFileList
+}
+
 func (d *directory) Map() map[string]interface{} {
 	return d.data
 }
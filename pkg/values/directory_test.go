@@ -98,7 +98,7 @@ func TestDirectory(t *testing.T) {
 	marshalMethods := []string{"JSON", "YAML"}
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			d, err := NewDirectory("testdata/directory/"+tc.groupVarsDir, tc.groups, true, ejson.Settings{})
+			d, err := NewDirectory("testdata/directory/"+tc.groupVarsDir, tc.groups, Options{SkipEval: true})
 			assert.NilError(t, err)
 			got := d.Map()
 			assert.NilError(t, err)
@@ -121,3 +121,158 @@ func TestDirectory(t *testing.T) {
 		})
 	}
 }
+
+func TestDirectoryHostVars(t *testing.T) {
+	tests := map[string]struct {
+		groups   []string
+		host     string
+		expected map[string]interface{}
+	}{
+		"host-vars": {
+			groups:   []string{"all"},
+			host:     "cluster-01",
+			expected: map[string]interface{}{"source": "host", "all": true, "host": true, "host_dir": true},
+		},
+		"host-vars-after-group": {
+			groups:   []string{"all", "cluster-01"},
+			host:     "cluster-01",
+			expected: map[string]interface{}{"source": "host", "all": true, "group": true, "host": true, "host_dir": true},
+		},
+		"no-host-vars": {
+			groups:   []string{"all", "cluster-01"},
+			host:     "cluster-02",
+			expected: map[string]interface{}{"source": "group", "all": true, "group": true},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			options := Options{
+				SkipEval:    true,
+				HostVarsDir: "testdata/hostvars/host_vars",
+				Host:        tc.host,
+			}
+			d, err := NewDirectory("testdata/hostvars/group_vars", tc.groups, options)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expected, d.Map())
+		})
+	}
+}
+
+func TestDirectoryMergeMode(t *testing.T) {
+	tests := map[string]struct {
+		mergeMode   string
+		expected    map[string]interface{}
+		errExpected bool
+	}{
+		"default": {
+			mergeMode: "",
+			expected: map[string]interface{}{
+				"vars": map[string]interface{}{
+					"list":  []interface{}{"(( append ))", "c"},
+					"items": []interface{}{map[string]interface{}{"name": "b", "value": "group-02"}},
+				},
+			},
+		},
+		"override": {
+			mergeMode: MergeModeOverride,
+			expected: map[string]interface{}{
+				"vars": map[string]interface{}{
+					"list":  []interface{}{"(( append ))", "c"},
+					"items": []interface{}{map[string]interface{}{"name": "b", "value": "group-02"}},
+				},
+			},
+		},
+		"spruce": {
+			mergeMode: MergeModeSpruce,
+			expected: map[string]interface{}{
+				"vars": map[string]interface{}{
+					"list": []interface{}{"a", "b", "c"},
+					"items": []interface{}{
+						map[string]interface{}{"name": "a", "value": "group-01"},
+						map[string]interface{}{"name": "b", "value": "group-02"},
+					},
+				},
+			},
+		},
+		"unknown": {
+			mergeMode:   "unknown",
+			errExpected: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			options := Options{
+				SkipEval:  true,
+				MergeMode: tc.mergeMode,
+			}
+			d, err := NewDirectory("testdata/mergemode", []string{"group-01", "group-02"}, options)
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tc.expected, d.Map())
+		})
+	}
+}
+
+func TestDirectoryContext(t *testing.T) {
+	options := Options{Host: "cluster-01", Ejson: ejson.Settings{KeyDirs: []string{"testdata/keydir"}}}
+	d, err := NewDirectory("testdata/context", []string{"all", "prod"}, options)
+	assert.NilError(t, err)
+
+	expected := map[string]interface{}{
+		"vars": map[string]interface{}{
+			"entry":  "cluster-01",
+			"groups": []interface{}{"all", "prod"},
+			"prod":   true,
+			"secret": "value1",
+		},
+	}
+	assert.DeepEqual(t, expected, d.Map())
+}
+
+func TestDirectoryOverlays(t *testing.T) {
+	options := Options{
+		SkipEval: true,
+		Overlays: []string{"testdata/overlays/team"},
+	}
+	v, err := New("testdata/overlays/platform", []string{"all", "prod", "team"}, options)
+	assert.NilError(t, err)
+
+	// each group is resolved across all directories before the next group
+	expectedFiles := []string{
+		"testdata/overlays/platform/all.yml",
+		"testdata/overlays/team/all.yml",
+		"testdata/overlays/platform/prod.yml",
+		"testdata/overlays/team/team.yml",
+	}
+	assert.DeepEqual(t, expectedFiles, v.Files())
+
+	expected := map[string]interface{}{
+		"vars": map[string]interface{}{
+			"name":  "team",
+			"layer": "platform",
+			"prod":  "platform",
+			"team":  true,
+		},
+	}
+	assert.DeepEqual(t, expected, v.Map())
+
+	provenances := v.Explain(nil, "vars.name")
+	assert.Equal(t, 1, len(provenances))
+	assert.Equal(t, "testdata/overlays/team/all.yml", provenances[0].File)
+
+	// without explicit groups, the groups of all directories are used
+	v, err = New("testdata/overlays/platform", []string{}, options)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, expectedFiles, v.Files())
+
+	// overlays require a values directory
+	_, err = New("testdata/overlays/platform/all.yml", []string{"all"}, options)
+	assert.Assert(t, err != nil)
+	_, err = New("testdata/overlays/platform", []string{"all"}, Options{Overlays: []string{"testdata/overlays/team/all.yml"}})
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,141 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"sort"
+	"strings"
+)
+
+// newSource records the leaf values of a single (unevaluated) values file
+func newSource(path string, data map[string]interface{}) source {
+	leaves := map[string]interface{}{}
+	flatten(data, "", leaves)
+	return source{
+		path:   path,
+		leaves: leaves,
+	}
+}
+
+// flatten adds all leaf values (everything but maps) of data to result,
+// using the dot separated path of the leaf as key
+func flatten(data map[string]interface{}, prefix string, result map[string]interface{}) {
+	for key, value := range data {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+		if m, ok := value.(map[string]interface{}); ok && len(m) > 0 {
+			flatten(m, path, result)
+			continue
+		}
+		result[path] = value
+	}
+}
+
+/*
+explain determines the provenance of all leaf values of data below
+the given path (all leaves if path is empty) based on the ordered list of
+sources data was merged from.
+
+A source sets a leaf if it contains the leaf or one of its parents
+as leaf.
+*/
+func explain(sources []source, data map[string]interface{}, path string) []Provenance {
+	leaves := map[string]interface{}{}
+	flatten(data, "", leaves)
+
+	paths := []string{}
+	for leaf := range leaves {
+		if path == "" || leaf == path || strings.HasPrefix(leaf, path+".") {
+			paths = append(paths, leaf)
+		}
+	}
+	sort.Strings(paths)
+
+	result := []Provenance{}
+	for _, leaf := range paths {
+		provenance := Provenance{
+			Path:      leaf,
+			Value:     leaves[leaf],
+			Overrides: []string{},
+		}
+
+		var raw interface{}
+		for _, src := range sources {
+			value, ok := src.lookup(leaf)
+			if !ok {
+				continue
+			}
+			if provenance.File != "" {
+				provenance.Overrides = append(provenance.Overrides, provenance.File)
+			}
+			provenance.File = src.path
+			raw = value
+		}
+		provenance.Spruce = isSpruceOperator(raw)
+
+		result = append(result, provenance)
+	}
+	return result
+}
+
+// lookup returns the value the source sets for the given leaf path
+// (either directly or by setting one of its parents)
+func (s source) lookup(path string) (interface{}, bool) {
+	for {
+		if value, ok := s.leaves[path]; ok {
+			return value, true
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return nil, false
+		}
+		path = path[:i]
+	}
+}
+
+func isSpruceOperator(value interface{}) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	s = strings.TrimSpace(s)
+	return strings.HasPrefix(s, "((") && strings.HasSuffix(s, "))")
+}
+
+// Explain returns the provenance of all leaf values of data below the given
+// dot separated path. If data is nil, the values of the file are used.
+func (f *file) Explain(data map[string]interface{}, path string) []Provenance {
+	if data == nil {
+		data = f.data
+	}
+	sources := []source{f.source}
+	if len(f.extraVars) > 0 {
+		sources = append(sources, newSource(ExtraVarsSource, f.extraVars))
+	}
+	return explain(sources, data, path)
+}
+
+// Explain returns the provenance of all leaf values of data below the given
+// dot separated path. If data is nil, the values of the directory are used.
+func (d *directory) Explain(data map[string]interface{}, path string) []Provenance {
+	if data == nil {
+		data = d.data
+	}
+	return explain(d.sources, data, path)
+}
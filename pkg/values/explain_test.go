@@ -0,0 +1,71 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestExplain(t *testing.T) {
+	groups := []string{"group-01", "group-02"}
+	d, err := NewDirectory("testdata/explain", groups, Options{SkipEval: false})
+	assert.NilError(t, err)
+
+	group01 := "testdata/explain/group-01.yml"
+	group02 := "testdata/explain/group-02.yml"
+
+	tests := map[string]struct {
+		path     string
+		expected []Provenance
+	}{
+		"all": {
+			path: "",
+			expected: []Provenance{
+				{Path: "vars.empty", File: group02, Overrides: []string{}, Value: ""},
+				{Path: "vars.list", File: group01, Overrides: []string{}, Value: []interface{}{"a", "b"}},
+				{Path: "vars.name", File: group02, Overrides: []string{group01}, Value: "group-02"},
+				{Path: "vars.nested.key1", File: group02, Overrides: []string{group01}, Spruce: true, Value: "group-02-nested"},
+				{Path: "vars.nested.key2", File: group02, Overrides: []string{group01}, Value: ""},
+			},
+		},
+		"path": {
+			path: "vars.nested",
+			expected: []Provenance{
+				{Path: "vars.nested.key1", File: group02, Overrides: []string{group01}, Spruce: true, Value: "group-02-nested"},
+				{Path: "vars.nested.key2", File: group02, Overrides: []string{group01}, Value: ""},
+			},
+		},
+		"leaf": {
+			path: "vars.list",
+			expected: []Provenance{
+				{Path: "vars.list", File: group01, Overrides: []string{}, Value: []interface{}{"a", "b"}},
+			},
+		},
+		"missing": {
+			path:     "vars.missing",
+			expected: []Provenance{},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, d.Explain(nil, tc.path))
+		})
+	}
+}
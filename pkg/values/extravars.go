@@ -0,0 +1,110 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/imdario/mergo"
+	"sigs.k8s.io/yaml"
+)
+
+/*
+ParseExtraVars parses ansible style extra vars. Each given argument is
+either
+
+ * key=value: value (parsed as yaml) is set at the dot separated path key
+ * @file: the values of the given yaml or json file
+ * a yaml or json map (e.g. '{"key": "value"}')
+
+Later arguments override earlier ones.
+*/
+func ParseExtraVars(args []string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for _, arg := range args {
+		vars, err := parseExtraVar(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid extra vars '%s': %s", arg, err)
+		}
+		err = mergo.Merge(&result, vars, mergo.WithOverride)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func parseExtraVar(arg string) (map[string]interface{}, error) {
+	trimmed := strings.TrimSpace(arg)
+	switch {
+	case strings.HasPrefix(trimmed, "@"):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(trimmed, "@"))
+		if err != nil {
+			return nil, err
+		}
+		return parseExtraVarsMap(data)
+	case strings.HasPrefix(trimmed, "{"):
+		return parseExtraVarsMap([]byte(trimmed))
+	}
+
+	i := strings.Index(arg, "=")
+	if i < 1 {
+		return nil, fmt.Errorf("expected key=value, @file or a yaml / json map")
+	}
+	key := arg[:i]
+	value, err := parseExtraVarsValue(arg[i+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(key, ".")
+	for j := len(parts) - 1; j >= 0; j-- {
+		if parts[j] == "" {
+			return nil, fmt.Errorf("empty key in '%s'", key)
+		}
+		value = map[string]interface{}{parts[j]: value}
+	}
+	return value.(map[string]interface{}), nil
+}
+
+func parseExtraVarsMap(data []byte) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := yaml.Unmarshal(data, &result)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	return result, nil
+}
+
+// parseExtraVarsValue parses the value of a key=value extra var as
+// yaml, an empty value is kept as empty string
+func parseExtraVarsValue(raw string) (interface{}, error) {
+	if strings.TrimSpace(raw) == "" {
+		return raw, nil
+	}
+	var value interface{}
+	err := yaml.Unmarshal([]byte(raw), &value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s': %s", raw, err)
+	}
+	return value, nil
+}
@@ -0,0 +1,126 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"gotest.tools/assert"
+)
+
+func TestParseExtraVars(t *testing.T) {
+	tests := map[string]struct {
+		args     []string
+		err      bool
+		expected map[string]interface{}
+	}{
+		"key-value": {
+			args:     []string{"key=value=x"},
+			expected: map[string]interface{}{"key": "value=x"},
+		},
+		"dotted-key": {
+			args: []string{"vars.a.b=1", "vars.a.c=2"},
+			expected: map[string]interface{}{
+				"vars": map[string]interface{}{"a": map[string]interface{}{"b": float64(1), "c": float64(2)}},
+			},
+		},
+		"yaml-value": {
+			args: []string{"a=true", "b=[1, x]", "c={d: e}", "f=null", "g="},
+			expected: map[string]interface{}{
+				"a": true,
+				"b": []interface{}{float64(1), "x"},
+				"c": map[string]interface{}{"d": "e"},
+				"f": nil,
+				"g": "",
+			},
+		},
+		"quoted-value": {
+			args:     []string{`a="3"`},
+			expected: map[string]interface{}{"a": "3"},
+		},
+		"invalid-value": {
+			args: []string{"a=[1"},
+			err:  true,
+		},
+		"json": {
+			args:     []string{`{"vars": {"a": 1, "b": [true]}}`},
+			expected: map[string]interface{}{"vars": map[string]interface{}{"a": float64(1), "b": []interface{}{true}}},
+		},
+		"file": {
+			args:     []string{"@testdata/extravars/vars.yml"},
+			expected: map[string]interface{}{"vars": map[string]interface{}{"a": "file", "list": []interface{}{float64(1), float64(2)}}},
+		},
+		"override": {
+			args:     []string{"@testdata/extravars/vars.yml", "vars.a=cli"},
+			expected: map[string]interface{}{"vars": map[string]interface{}{"a": "cli", "list": []interface{}{float64(1), float64(2)}}},
+		},
+		"missing-value": {
+			args: []string{"key"},
+			err:  true,
+		},
+		"empty-key": {
+			args: []string{"vars..a=1"},
+			err:  true,
+		},
+		"missing-file": {
+			args: []string{"@testdata/extravars/missing.yml"},
+			err:  true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			result, err := ParseExtraVars(tt.args)
+			if tt.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExtraVars(t *testing.T) {
+	extraVars := map[string]interface{}{
+		"vars": map[string]interface{}{"name": "cli"},
+	}
+	options := Options{
+		SkipEval:  true,
+		Ejson:     ejson.Settings{},
+		ExtraVars: extraVars,
+	}
+
+	for name, path := range map[string]string{"directory": "testdata/locate", "file": "testdata/locate/cluster-01.yml"} {
+		path := path
+		t.Run(name, func(t *testing.T) {
+			v, err := New(path, []string{"all", "cluster-01"}, options)
+			assert.NilError(t, err)
+
+			vars := v.Map()["vars"].(map[string]interface{})
+			assert.Equal(t, "cli", vars["name"])
+
+			provenances := v.Explain(nil, "vars.name")
+			assert.Equal(t, 1, len(provenances))
+			assert.Equal(t, ExtraVarsSource, provenances[0].File)
+		})
+	}
+	// the extra vars must not be modified
+	assert.DeepEqual(t, map[string]interface{}{"vars": map[string]interface{}{"name": "cli"}}, extraVars)
+}
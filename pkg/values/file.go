@@ -18,70 +18,146 @@ package values
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"path/filepath"
 
-	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/internal/third_party/deepcopy"
 	"github.com/bedag/kusible/internal/wrapper/spruce"
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/pkg/wrapper/sops"
+	"github.com/imdario/mergo"
 
 	"sigs.k8s.io/yaml"
 )
 
-func NewFile(path string, skipEval bool, ejsonSettings ejson.Settings) (*file, error) {
+// NewFile loads the values file at path
+func NewFile(path string, options Options) (*file, error) {
+	return newFile(path, options, []string{})
+}
+
+// newFile loads the file at path, the groups are only used
+// to render templates
+func newFile(path string, options Options, groups []string) (*file, error) {
 	result := &file{
-		path:     path,
-		ejson:    ejsonSettings,
-		skipEval: skipEval,
+		path:      path,
+		ejson:     options.Ejson,
+		sops:      options.Sops,
+		skipEval:  options.SkipEval,
+		cache:     options.Cache,
+		extraVars: options.ExtraVars,
+		templates: options.Templates,
+		template: TemplateData{
+			Entry:  options.Host,
+			Groups: groups,
+		},
 	}
 	err := result.loadMap()
 	return result, err
 }
 
 func (f *file) load() ([]byte, error) {
-	var data []byte
 	// Check if the current path is an ejson file and if so, try
 	// to decrypt it. If it cannot be decrypted, continue as there
 	// is no harm in using the encrypted values
-	isEjson, err := filepath.Match("*.ejson", filepath.Base(f.path))
+	if f.isEjson() {
+		return ejson.ReadFile(f.path, f.ejson)
+	}
+	return ioutil.ReadFile(f.path)
+}
+
+// parse loads the file and unmarshals its (decrypted) content
+func (f *file) parse() (map[string]interface{}, error) {
+	data, err := f.load()
 	if err != nil {
 		return nil, err
 	}
-	if isEjson {
-		data, err = ejson.ReadFile(f.path, f.ejson)
-	} else {
-		data, err = ioutil.ReadFile(f.path)
+
+	isTemplate := f.isTemplate()
+	if isTemplate {
+		data, err = renderTemplate(f.path, data, f.template)
+		if err != nil {
+			return nil, err
+		}
 	}
+
+	var result map[string]interface{}
+	err = yaml.Unmarshal(data, &result)
 	if err != nil {
+		if isTemplate {
+			return nil, fmt.Errorf("failed to parse rendered values template %s (line numbers refer to the rendered template): %s", f.path, err)
+		}
 		return nil, err
 	}
-	return data, nil
+
+	// Files are treated as sops encrypted if either their name
+	// says so (*.sops.yaml) or if they contain sops metadata. The
+	// sops executable decrypts the file itself, which is why rendered
+	// templates are never decrypted.
+	if !f.isEjson() && !isTemplate && (sops.IsSopsFile(f.path) || sops.IsEncrypted(result)) {
+		result, err = sops.Decrypt(f.path, result, f.sops)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if result == nil {
+		result = make(map[string]interface{})
+	}
+	return result, nil
 }
 
 func (f *file) loadMap() error {
-	data, err := f.load()
-	if err != nil {
-		return err
+	var err error
+	// templates are rendered for a specific entry and cannot be cached
+	if f.cache != nil && !f.isTemplate() {
+		f.data, err = f.cache.load(f.path, f.parse)
+	} else {
+		f.data, err = f.parse()
 	}
-
-	err = yaml.Unmarshal(data, &f.data)
 	if err != nil {
 		return err
 	}
-
-	if f.data == nil {
-		f.data = make(map[string]interface{})
+	f.source = newSource(f.path, f.data)
+
+	// extra vars override the values of the file
+	if len(f.extraVars) > 0 {
+		extraVars, err := deepcopy.Map(f.extraVars)
+		if err != nil {
+			return err
+		}
+		err = mergo.Merge(&f.data, extraVars, mergo.WithOverride)
+		if err != nil {
+			return err
+		}
 	}
 
 	// if we want to skip the spruce evaluation, skip the evaluator
 	// alltogether as an Evaluator with SkipEval: true only prunes / cherrypicks,
 	// something we do not need here
 	if !f.skipEval {
-		err := spruce.Eval(&f.data, false, []string{})
-		return err
+		ctx := &spruce.Context{Ejson: f.ejson, BaseDir: BaseDir(f)}
+		err := spruce.EvalWithContext(&f.data, false, []string{}, ctx)
+		return AnnotateEvalError(f, err)
 	}
 	return nil
 }
 
+// isEjson returns true if the file is (possibly) ejson encrypted
+func (f *file) isEjson() bool {
+	return filepath.Ext(f.path) == ".ejson"
+}
+
+// isTemplate returns true if the file has to be rendered as go template
+func (f *file) isTemplate() bool {
+	return f.templates && isTemplate(f.path)
+}
+
+// Files returns the files the values were merged from
+func (f *file) Files() []string {
+	return []string{f.path}
+}
+
 func (f *file) Map() map[string]interface{} {
 	return f.data
 }
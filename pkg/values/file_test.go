@@ -19,9 +19,11 @@ package values
 import (
 	"io/ioutil"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/pkg/wrapper/sops"
 	"gotest.tools/assert"
 	"sigs.k8s.io/yaml"
 )
@@ -60,12 +62,12 @@ func TestFile(t *testing.T) {
 	}
 
 	ejsonSettings := ejson.Settings{
-		KeyDir: "testdata/keydir",
+		KeyDirs: []string{"testdata/keydir"},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			f, err := NewFile("testdata/file/"+tc.input, tc.skipEval, ejsonSettings)
+			f, err := NewFile("testdata/file/"+tc.input, Options{SkipEval: tc.skipEval, Ejson: ejsonSettings})
 			assert.NilError(t, err)
 			got := f.Map()
 			assert.NilError(t, err)
@@ -90,3 +92,14 @@ func TestFile(t *testing.T) {
 		})
 	}
 }
+
+func TestFileSopsMetadata(t *testing.T) {
+	// with decryption skipped, the encrypted data is used
+	// without the sops metadata
+	f, err := NewFile("testdata/sops/metadata.yml", Options{SkipEval: true, Sops: sops.Settings{SkipDecrypt: true}})
+	assert.NilError(t, err)
+
+	_, ok := f.Map()["sops"]
+	assert.Assert(t, !ok)
+	assert.Assert(t, strings.HasPrefix(f.Map()["secret"].(string), "ENC[AES256_GCM,"))
+}
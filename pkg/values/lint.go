@@ -0,0 +1,296 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+)
+
+// NewLinter creates a linter for the values in the given group vars directory
+// and its overlays. Only the overlays and the ejson settings of the options
+// are used.
+func NewLinter(path string, options Options) *Linter {
+	return &Linter{
+		paths:   append([]string{path}, options.Overlays...),
+		ejson:   options.Ejson,
+		issues:  []LintIssue{},
+		groups:  map[string]bool{},
+		files:   map[string]bool{},
+		shadows: map[string]map[string]*shadow{},
+	}
+}
+
+/*
+Add lints the values of the given inventory entry. The values must have been
+compiled without spruce evaluation. data is the unevaluated data the values
+are evaluated with (e.g. the values merged with the cluster inventory),
+if it is nil, the values are used.
+*/
+func (l *Linter) Add(entry string, groups []string, v Values, data map[string]interface{}) {
+	if data == nil {
+		data = v.Map()
+	}
+
+	for _, group := range groups {
+		l.groups[group] = true
+	}
+	for _, file := range v.Files() {
+		l.files[file] = true
+	}
+
+	for _, provenance := range v.Explain(data, "") {
+		for _, ref := range undefinedGrabs(provenance.Value, data) {
+			l.issues = append(l.issues, LintIssue{
+				Type:    LintIssueUndefinedGrab,
+				Entry:   entry,
+				File:    provenance.File,
+				Path:    provenance.Path,
+				Message: fmt.Sprintf("(( grab %s )) references an undefined path", ref),
+			})
+		}
+
+		if provenance.File != "" {
+			l.shadow(provenance.File, provenance.Path).set = true
+		}
+		for _, file := range provenance.Overrides {
+			l.shadow(file, provenance.Path).overridden++
+		}
+	}
+}
+
+// SkipUnusedGroups disables the check for unused group files / directories.
+// The check only gives correct results if all inventory entries were added,
+// it should be skipped if only a subset of the entries is linted.
+func (l *Linter) SkipUnusedGroups() {
+	l.skipUnusedGroups = true
+}
+
+func (l *Linter) shadow(file string, path string) *shadow {
+	if _, ok := l.shadows[file]; !ok {
+		l.shadows[file] = map[string]*shadow{}
+	}
+	if _, ok := l.shadows[file][path]; !ok {
+		l.shadows[file][path] = &shadow{}
+	}
+	return l.shadows[file][path]
+}
+
+/*
+Issues returns all issues found for the added entries. Besides the issues
+found for the single entries this includes
+
+ * values that are overridden for all entries using the file that sets them
+ * group files / directories not used by any entry (see SkipUnusedGroups)
+ * ejson files used by any entry that cannot be decrypted
+*/
+func (l *Linter) Issues() ([]LintIssue, error) {
+	result := append([]LintIssue{}, l.issues...)
+
+	files := make([]string, 0, len(l.shadows))
+	for file := range l.shadows {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		paths := make([]string, 0, len(l.shadows[file]))
+		for path := range l.shadows[file] {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			s := l.shadows[file][path]
+			if s.set || s.overridden < 1 {
+				continue
+			}
+			result = append(result, LintIssue{
+				Type:    LintIssueShadowed,
+				File:    file,
+				Path:    path,
+				Message: fmt.Sprintf("value is overridden by more specific files for all %d entries using it", s.overridden),
+			})
+		}
+	}
+
+	if !l.skipUnusedGroups {
+		unused, err := l.unusedGroupFiles()
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range unused {
+			result = append(result, LintIssue{
+				Type:    LintIssueUnusedGroup,
+				File:    file,
+				Message: "group is not used by any inventory entry",
+			})
+		}
+	}
+
+	files = make([]string, 0, len(l.files))
+	for file := range l.files {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	settings := l.ejson
+	settings.Strict = true
+	for _, file := range files {
+		if settings.SkipDecrypt || filepath.Ext(file) != ".ejson" {
+			continue
+		}
+		if _, err := ejson.ReadFile(file, settings); err != nil {
+			result = append(result, LintIssue{
+				Type:    LintIssueUndecryptable,
+				File:    file,
+				Message: err.Error(),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// unusedGroupFiles returns all group files / directories in the group vars
+// directories that belong to groups none of the added entries is a member of
+func (l *Linter) unusedGroupFiles() ([]string, error) {
+	result := []string{}
+	for _, path := range l.paths {
+		used := map[string]bool{}
+		for group := range l.groups {
+			used[filepath.Join(path, group)] = true
+			files, _ := DirectoryDataFiles(path, group)
+			for _, file := range files {
+				used[file] = true
+			}
+		}
+
+		candidates, _ := DirectoryDataFiles(path, "*")
+		elements, err := filepath.Glob(filepath.Join(path, "*"))
+		if err != nil {
+			return nil, err
+		}
+		for _, element := range elements {
+			if stat, err := os.Stat(element); err == nil && stat.IsDir() {
+				candidates = append(candidates, element)
+			}
+		}
+
+		for _, candidate := range candidates {
+			if !used[candidate] {
+				result = append(result, candidate)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+/*
+undefinedGrabs returns the references of a (( grab )) operator that cannot
+be resolved in data. References separated by || are alternatives, they are
+only reported if none of them (including literals) can be resolved.
+
+Only values that are exactly a (( grab ... )) operator are checked, grabs
+used as arguments of other operators (e.g. (( concat ))) are not detected.
+*/
+func undefinedGrabs(value interface{}, data map[string]interface{}) []string {
+	if !isSpruceOperator(value) {
+		return nil
+	}
+	op := strings.TrimSpace(value.(string))
+	args := strings.Fields(strings.TrimSpace(op[2 : len(op)-2]))
+	if len(args) < 2 || args[0] != "grab" {
+		return nil
+	}
+
+	alternatives := false
+	refs := []string{}
+	undefined := []string{}
+	for _, arg := range args[1:] {
+		if arg == "||" {
+			alternatives = true
+			continue
+		}
+		refs = append(refs, arg)
+		if isLiteral(arg) {
+			continue
+		}
+		if _, ok := lookupPath(data, strings.TrimPrefix(arg, "$.")); !ok {
+			undefined = append(undefined, arg)
+		}
+	}
+
+	if alternatives && len(undefined) < len(refs) {
+		return nil
+	}
+	return undefined
+}
+
+// isLiteral returns true if the given spruce operator argument is
+// not a reference
+func isLiteral(arg string) bool {
+	switch arg {
+	case "nil", "null", "~", "true", "false":
+		return true
+	}
+	if strings.ContainsAny(arg, "\"'") || strings.HasPrefix(arg, "$") && !strings.HasPrefix(arg, "$.") {
+		return true
+	}
+	_, err := strconv.ParseFloat(arg, 64)
+	return err == nil
+}
+
+// lookupPath returns the value at the given dot separated path. List
+// elements can be addressed by index or by the value of their name key.
+func lookupPath(data map[string]interface{}, path string) (interface{}, bool) {
+	var current interface{} = data
+	for _, key := range strings.Split(path, ".") {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			current = value
+		case []interface{}:
+			found := false
+			if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node) {
+				current = node[i]
+				found = true
+			} else {
+				for _, element := range node {
+					if m, ok := element.(map[string]interface{}); ok && m["name"] == key {
+						current = element
+						found = true
+						break
+					}
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
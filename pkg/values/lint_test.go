@@ -0,0 +1,132 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"gotest.tools/assert"
+)
+
+func TestLinter(t *testing.T) {
+	entries := map[string][]string{
+		"entry-01": {"all", "cluster-01", "secret"},
+		"entry-02": {"all", "cluster-01"},
+	}
+	settings := ejson.Settings{KeyDirs: []string{"testdata/lint"}}
+
+	linter := NewLinter("testdata/lint", Options{Ejson: settings})
+	for _, name := range []string{"entry-01", "entry-02"} {
+		v, err := NewDirectory("testdata/lint", entries[name], Options{SkipEval: true, Ejson: settings})
+		assert.NilError(t, err)
+		linter.Add(name, entries[name], v, nil)
+	}
+
+	issues, err := linter.Issues()
+	assert.NilError(t, err)
+
+	expected := []LintIssue{
+		{Type: LintIssueUndefinedGrab, Entry: "entry-01", File: "testdata/lint/all.yml", Path: "vars.undefined"},
+		{Type: LintIssueUndefinedGrab, Entry: "entry-02", File: "testdata/lint/all.yml", Path: "vars.undefined"},
+		{Type: LintIssueShadowed, File: "testdata/lint/all.yml", Path: "vars.shadowed"},
+		{Type: LintIssueUnusedGroup, File: "testdata/lint/unused.yml"},
+		{Type: LintIssueUndecryptable, File: "testdata/lint/secret.ejson"},
+	}
+	assert.Equal(t, len(expected), len(issues))
+	for i, issue := range issues {
+		assert.Equal(t, expected[i].Type, issue.Type)
+		assert.Equal(t, expected[i].Entry, issue.Entry)
+		assert.Equal(t, expected[i].File, issue.File)
+		assert.Equal(t, expected[i].Path, issue.Path)
+	}
+}
+
+func TestLinterSkipUnusedGroups(t *testing.T) {
+	groups := []string{"all", "cluster-01"}
+	linter := NewLinter("testdata/lint", Options{})
+	linter.SkipUnusedGroups()
+	v, err := NewDirectory("testdata/lint", groups, Options{SkipEval: true})
+	assert.NilError(t, err)
+	linter.Add("entry-02", groups, v, nil)
+
+	issues, err := linter.Issues()
+	assert.NilError(t, err)
+	for _, issue := range issues {
+		assert.Assert(t, issue.Type != LintIssueUnusedGroup, "unexpected issue for %s", issue.File)
+	}
+}
+
+func TestLinterOverlays(t *testing.T) {
+	settings := ejson.Settings{}
+	options := Options{SkipEval: true, Overlays: []string{"testdata/overlays/team"}}
+
+	linter := NewLinter("testdata/overlays/platform", Options{Ejson: settings, Overlays: options.Overlays})
+	v, err := New("testdata/overlays/platform", []string{"all"}, options)
+	assert.NilError(t, err)
+	linter.Add("entry-01", []string{"all"}, v, nil)
+
+	issues, err := linter.Issues()
+	assert.NilError(t, err)
+
+	expected := []LintIssue{
+		{Type: LintIssueShadowed, File: "testdata/overlays/platform/all.yml", Path: "vars.name"},
+		{Type: LintIssueUnusedGroup, File: "testdata/overlays/platform/prod.yml"},
+		{Type: LintIssueUnusedGroup, File: "testdata/overlays/team/team.yml"},
+	}
+	assert.Equal(t, len(expected), len(issues))
+	for i, issue := range issues {
+		assert.Equal(t, expected[i].Type, issue.Type)
+		assert.Equal(t, expected[i].File, issue.File)
+		assert.Equal(t, expected[i].Path, issue.Path)
+	}
+}
+
+func TestUndefinedGrabs(t *testing.T) {
+	data := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": "c",
+			"list": []interface{}{
+				map[string]interface{}{"name": "first"},
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		value    interface{}
+		expected []string
+	}{
+		"no operator":       {value: "a.b", expected: nil},
+		"other operator":    {value: "(( concat a.missing ))", expected: nil},
+		"defined":           {value: "(( grab a.b ))", expected: []string{}},
+		"root prefix":       {value: "(( grab $.a.b ))", expected: []string{}},
+		"list index":        {value: "(( grab a.list.0.name ))", expected: []string{}},
+		"list name":         {value: "(( grab a.list.first ))", expected: []string{}},
+		"undefined":         {value: "(( grab a.missing ))", expected: []string{"a.missing"}},
+		"multiple":          {value: "(( grab a.b a.missing ))", expected: []string{"a.missing"}},
+		"alternative":       {value: "(( grab a.missing || a.b ))", expected: nil},
+		"literal":           {value: `(( grab a.missing || "default" ))`, expected: nil},
+		"all alternatives":  {value: "(( grab a.missing || a.other ))", expected: []string{"a.missing", "a.other"}},
+		"non string values": {value: 1, expected: nil},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.DeepEqual(t, tc.expected, undefinedGrabs(tc.value, data))
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/bedag/kusible/internal/wrapper/spruce"
+	"gopkg.in/yaml.v3"
+)
+
+// Locate returns the location of the value at the given dot separated
+// path in the values file that set it last. The line is the line of the
+// deepest part of the path found in that file (0 if it cannot be determined).
+func Locate(v Values, path string) (Location, bool) {
+	file, ok := locateFile(v, path)
+	if !ok {
+		return Location{}, false
+	}
+	return Location{
+		File: file,
+		Line: line(file, path),
+	}, true
+}
+
+// BaseDir returns a function returning the directory of the values
+// file that set the value at the given dot separated path last, used
+// as base directory for relative paths of the kusible spruce operators.
+// The function returns an empty string for extra vars and values it
+// cannot locate.
+func BaseDir(v Values) func(path string) string {
+	return func(path string) string {
+		file, ok := locateFile(v, path)
+		if !ok || file == ExtraVarsSource {
+			return ""
+		}
+		return filepath.Dir(file)
+	}
+}
+
+// locateFile returns the values file that set the value at the
+// given dot separated path last
+func locateFile(v Values, path string) (string, bool) {
+	// lists are leaves for Explain(), so paths into lists are
+	// explained by their parents
+	for leaf := path; leaf != ""; {
+		for _, provenance := range v.Explain(nil, leaf) {
+			if leaf != path && provenance.Path != leaf {
+				break
+			}
+			if provenance.File == "" {
+				return "", false
+			}
+			return provenance.File, true
+		}
+		i := strings.LastIndex(leaf, ".")
+		if i < 0 {
+			break
+		}
+		leaf = leaf[:i]
+	}
+	return "", false
+}
+
+// AnnotateEvalError adds the location of each failed spruce operator
+// of a spruce evaluation error. Other errors are returned as is.
+func AnnotateEvalError(v Values, err error) error {
+	var evalErr *spruce.EvalError
+	if !errors.As(err, &evalErr) {
+		return err
+	}
+	for i, opErr := range evalErr.Errors {
+		if opErr.Path == "" || opErr.Source != "" {
+			continue
+		}
+		if location, ok := Locate(v, opErr.Path); ok {
+			evalErr.Errors[i].Source = location.String()
+		}
+	}
+	return err
+}
+
+func (l Location) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d", l.File, l.Line)
+	}
+	return l.File
+}
+
+// line returns the line of the deepest part of the given dot separated
+// path found in the given yaml (or json) file
+func line(file string, path string) int {
+	raw, err := ioutil.ReadFile(file)
+	if err != nil {
+		return 0
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil || len(doc.Content) == 0 {
+		return 0
+	}
+
+	node := doc.Content[0]
+	result := 0
+	for _, key := range strings.Split(path, ".") {
+		node = child(node, key)
+		if node == nil {
+			break
+		}
+		result = node.Line
+	}
+	return result
+}
+
+// child returns the node below the given mapping or sequence node
+// reachable with the given key (a map key or a list index)
+func child(node *yaml.Node, key string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				// report the line of the key, not the value
+				value := *node.Content[i+1]
+				value.Line = node.Content[i].Line
+				return &value
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(node.Content) {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
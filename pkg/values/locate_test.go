@@ -0,0 +1,64 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"github.com/bedag/kusible/internal/wrapper/spruce"
+	"gotest.tools/assert"
+)
+
+func TestLocate(t *testing.T) {
+	groups := []string{"all", "cluster-01"}
+	v, err := NewDirectory("testdata/locate", groups, Options{SkipEval: true})
+	assert.NilError(t, err)
+
+	tests := map[string]struct {
+		path     string
+		ok       bool
+		expected string
+	}{
+		"overridden": {path: "vars.name", ok: true, expected: "testdata/locate/cluster-01.yml:3"},
+		"list-index": {path: "vars.list.1", ok: true, expected: "testdata/locate/all.yml:6"},
+		"map":        {path: "vars", ok: true, expected: "testdata/locate/all.yml:2"},
+		"missing":    {path: "vars.missing", ok: false},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			location, ok := Locate(v, tt.path)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, location.String())
+			}
+		})
+	}
+}
+
+func TestAnnotateEvalError(t *testing.T) {
+	groups := []string{"all", "cluster-01"}
+	_, err := NewDirectory("testdata/locate", groups, Options{SkipEval: false})
+	assert.ErrorContains(t, err, "$.vars.broken: ")
+	assert.ErrorContains(t, err, "(testdata/locate/all.yml:7)")
+
+	evalErr, ok := err.(*spruce.EvalError)
+	assert.Assert(t, ok)
+	assert.Equal(t, 1, len(evalErr.Errors))
+	assert.Equal(t, "vars.broken", evalErr.Errors[0].Path)
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"sigs.k8s.io/yaml"
+)
+
+// templateExt are the extensions of values files rendered as go templates
+var templateExt = [...]string{".yml.tmpl", ".yaml.tmpl", ".yml.gotmpl", ".yaml.gotmpl"}
+
+// isTemplate returns true if the given file is a values template
+func isTemplate(path string) bool {
+	for _, ext := range templateExt {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// templateFuncs returns the functions available in values templates: all
+// repeatable sprig functions (https://masterminds.github.io/sprig/)
+// and toYaml
+func templateFuncs() template.FuncMap {
+	funcs := sprig.HermeticTxtFuncMap()
+	funcs["toYaml"] = func(v interface{}) (string, error) {
+		data, err := yaml.Marshal(v)
+		return strings.TrimSuffix(string(data), "\n"), err
+	}
+	return funcs
+}
+
+// renderTemplate renders the given values template. Errors contain
+// the path of the template and the line of the error.
+func renderTemplate(path string, data []byte, values TemplateData) ([]byte, error) {
+	tmpl, err := template.New(path).Funcs(templateFuncs()).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse values template: %s", err)
+	}
+
+	var result bytes.Buffer
+	err = tmpl.Execute(&result, values)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render values template: %s", err)
+	}
+	return result.Bytes(), nil
+}
@@ -0,0 +1,90 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package values
+
+import (
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTemplates(t *testing.T) {
+	tests := map[string]struct {
+		path      string
+		groups    []string
+		templates bool
+		err       string
+		expected  map[string]interface{}
+	}{
+		"disabled": {
+			path:     "testdata/templates/valid",
+			groups:   []string{"all"},
+			expected: map[string]interface{}{"name": "all", "replicas": 1},
+		},
+		"rendered": {
+			path:      "testdata/templates/valid",
+			groups:    []string{"all"},
+			templates: true,
+			expected: map[string]interface{}{
+				"name":       "entry-01",
+				"replicas":   1,
+				"namespaces": []interface{}{"ALL"},
+			},
+		},
+		"conditional": {
+			path:      "testdata/templates/valid",
+			groups:    []string{"all", "prod"},
+			templates: true,
+			expected: map[string]interface{}{
+				"name":       "entry-01",
+				"replicas":   3,
+				"namespaces": []interface{}{"ALL", "PROD"},
+			},
+		},
+		"missing-field": {
+			path:      "testdata/templates/broken",
+			groups:    []string{"all"},
+			templates: true,
+			err:       "testdata/templates/broken/all.yml.tmpl:4:",
+		},
+		"invalid-yaml": {
+			path:      "testdata/templates/invalid-yaml",
+			groups:    []string{"all"},
+			templates: true,
+			err:       "failed to parse rendered values template testdata/templates/invalid-yaml/all.yml.tmpl",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			options := Options{
+				SkipEval:  true,
+				Host:      "entry-01",
+				Templates: tt.templates,
+				Cache:     NewCache(),
+			}
+			v, err := NewDirectory(tt.path, tt.groups, options)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			assert.NilError(t, err)
+			assert.DeepEqual(t, tt.expected, v.Map()["vars"])
+		})
+	}
+}
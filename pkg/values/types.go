@@ -16,27 +16,214 @@ limitations under the License.
 
 package values
 
-import "github.com/bedag/kusible/pkg/wrapper/ejson"
+import (
+	"sync"
+	"time"
+
+	"github.com/bedag/kusible/pkg/wrapper/ejson"
+	"github.com/bedag/kusible/pkg/wrapper/sops"
+)
 
 type Values interface {
 	YAML() ([]byte, error)
 	JSON() ([]byte, error)
 	Map() map[string]interface{}
+	// Explain returns the provenance of the leaf values of data
+	// (usually the evaluated values) below the given path
+	Explain(data map[string]interface{}, path string) []Provenance
+	// Files returns the ordered list of files the values were merged from
+	Files() []string
+}
+
+// Provenance describes which file set a leaf value of the merged values
+type Provenance struct {
+	// Path is the dot separated path of the leaf
+	Path string
+	// File is the file that set the value last
+	File string
+	// Overrides lists the earlier files that also set the value
+	Overrides []string
+	// Spruce is true if the value was produced by a spruce operator
+	Spruce bool
+	Value  interface{}
+}
+
+// TemplateData is the data available in values templates
+type TemplateData struct {
+	// Entry is the name of the inventory entry (empty if the values
+	// are not compiled for an inventory entry)
+	Entry string
+	// Groups are the groups the values are compiled for
+	Groups []string
+}
+
+// ExtraVarsSource is the file name used to explain values
+// set by extra vars
+const ExtraVarsSource = "<extra vars>"
+
+// Location is a position in a values file
+type Location struct {
+	File string
+	// Line is 0 if the line is unknown
+	Line int
+}
+
+// LintIssue is a problem found by the Linter
+type LintIssue struct {
+	// Type is one of the LintIssue* constants
+	Type string
+	// Entry is the inventory entry the issue was found for (empty
+	// for issues not specific to a single entry)
+	Entry   string
+	File    string
+	Path    string
+	Message string
+}
+
+// Change describes a difference of a single leaf value between two
+// sets of values
+type Change struct {
+	// Path is the dot separated path of the leaf
+	Path string
+	// Type is one of the Change* constants
+	Type string
+	Old  interface{}
+	New  interface{}
+}
+
+// Linter collects lint issues of the values of multiple inventory entries
+type Linter struct {
+	paths            []string
+	ejson            ejson.Settings
+	issues           []LintIssue
+	groups           map[string]bool
+	files            map[string]bool
+	shadows          map[string]map[string]*shadow
+	skipUnusedGroups bool
+}
+
+// shadow tracks how often a value of a file was overridden by
+// more specific files
+type shadow struct {
+	overridden int
+	set        bool
+}
+
+// source holds the leaf values of a single values file
+type source struct {
+	path   string
+	leaves map[string]interface{}
+}
+
+const (
+	// ChangeAdded is used for leaves only present in the new values
+	ChangeAdded = "added"
+	// ChangeRemoved is used for leaves only present in the old values
+	ChangeRemoved = "removed"
+	// ChangeModified is used for leaves present in both values with
+	// different values
+	ChangeModified = "changed"
+)
+
+const (
+	// LintIssueUndefinedGrab is reported for (( grab )) operators
+	// referencing paths that do not exist
+	LintIssueUndefinedGrab = "undefined-grab"
+	// LintIssueShadowed is reported for values that are overridden by
+	// more specific files for all entries using the file
+	LintIssueShadowed = "shadowed"
+	// LintIssueUnusedGroup is reported for group files / directories
+	// that are not used by any entry
+	LintIssueUnusedGroup = "unused-group"
+	// LintIssueUndecryptable is reported for ejson files that
+	// cannot be decrypted
+	LintIssueUndecryptable = "undecryptable"
+)
+
+const (
+	// MergeModeOverride merges values files with mergo, values of more
+	// specific files override values (including lists) of less specific files
+	MergeModeOverride = "override"
+	// MergeModeSpruce merges values files with the spruce merge engine,
+	// supporting the spruce array operators between files
+	MergeModeSpruce = "spruce"
+)
+
+// Options control how values are compiled
+type Options struct {
+	SkipEval bool
+	Ejson    ejson.Settings
+	Sops     sops.Settings
+	// MergeMode controls how the files of a values directory are
+	// merged (MergeModeOverride if empty)
+	MergeMode string
+	// Overlays are additional values directories. For each group, the
+	// files of the overlays are merged (in the given order) after the
+	// files of the values directory. Overlays require a values directory.
+	Overlays []string
+	// HostVarsDir is a directory containing host specific values that
+	// are merged after all group values
+	HostVarsDir string
+	// Host is the name of the inventory entry used to look up
+	// the host specific values in HostVarsDir, it is also available
+	// as (( kusible_entry )) when evaluating the values
+	Host string
+	// Cache is used to share parsed files between multiple values,
+	// files are parsed again every time if it is nil
+	Cache *Cache
+	// Templates enables rendering *.yml.tmpl / *.yaml.gotmpl files
+	// as go templates (see TemplateData) before they are parsed
+	Templates bool
+	// ExtraVars are merged over all files with the highest precedence
+	// before the values are evaluated (see ParseExtraVars)
+	ExtraVars map[string]interface{}
+}
+
+// Cache holds the parsed (and decrypted) but unevaluated data of values
+// files. Entries are invalidated if the modification time or size of
+// the file changes. It is safe for concurrent use.
+type Cache struct {
+	mutex   sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	loaded  bool
+	data    map[string]interface{}
 }
 
 type file struct {
-	data     map[string]interface{}
-	path     string
-	ejson    ejson.Settings
-	skipEval bool
+	data      map[string]interface{}
+	source    source
+	path      string
+	ejson     ejson.Settings
+	sops      sops.Settings
+	skipEval  bool
+	cache     *Cache
+	extraVars map[string]interface{}
+	templates bool
+	// template is the data used to render the file if it is a template
+	template TemplateData
 }
 
 type directory struct {
 	data            map[string]interface{}
 	path            string
+	overlays        []string
 	groups          []string
 	ejson           ejson.Settings
+	sops            sops.Settings
 	skipEval        bool
+	hostVarsDir     string
+	host            string
+	mergeMode       string
+	cache           *Cache
+	extraVars       map[string]interface{}
+	templates       bool
 	files           []file
+	sources         []source
 	orderedFileList []string
 }
@@ -19,6 +19,7 @@ package values
 import (
 	"path/filepath"
 
+	"github.com/bedag/kusible/pkg/wrapper/sops"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -30,14 +31,26 @@ LoadMap method are considered. The operation is non-recursive.
 The pattern syntax is the same as the one for fmt.Match.
 */
 func DirectoryDataFiles(directory string, pattern string) ([]string, bool) {
-	dataFileExt := [...]string{".yml", ".yaml", ".json", ".ejson"}
+	return directoryDataFiles(directory, pattern, false)
+}
+
+// directoryDataFiles is the same as DirectoryDataFiles() but also
+// returns values templates (after all other files) if templates is true
+func directoryDataFiles(directory string, pattern string, templates bool) ([]string, bool) {
+	dataFileExt := [...]string{".yml", ".yaml", ".json", ".ejson", ".sops.yml", ".sops.yaml", ".sops.json"}
 	var dataFileGlobs []string
 
 	for _, ext := range dataFileExt {
 		dataFileGlobs = append(dataFileGlobs, pattern+ext)
 	}
+	if templates {
+		for _, ext := range templateExt {
+			dataFileGlobs = append(dataFileGlobs, pattern+ext)
+		}
+	}
 
 	var fileList []string
+	seen := map[string]bool{}
 	ok := true
 
 	for _, glob := range dataFileGlobs {
@@ -47,8 +60,18 @@ func DirectoryDataFiles(directory string, pattern string) ([]string, bool) {
 				"pattern": glob,
 			}).Warn(err.Error())
 			ok = false
-		} else {
-			fileList = append(fileList, files...)
+			continue
+		}
+		for _, file := range files {
+			// *.sops.yaml files also match the *.yaml glob but should
+			// only be added (with a higher priority) by the *.sops.yaml glob
+			if sops.IsSopsFile(file) && !sops.IsSopsFile(glob) {
+				continue
+			}
+			if !seen[file] {
+				seen[file] = true
+				fileList = append(fileList, file)
+			}
 		}
 	}
 
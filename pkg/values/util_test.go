@@ -41,3 +41,15 @@ func TestUtilDataFiles(t *testing.T) {
 	sort.Strings(files)
 	assert.DeepEqual(t, expected, files)
 }
+
+func TestUtilDataFilesSops(t *testing.T) {
+	files, ok := DirectoryDataFiles("testdata/util-sops", "*")
+	assert.Assert(t, ok)
+	expected := []string{
+		"testdata/util-sops/test.yaml",
+		"testdata/util-sops/test.ejson",
+		"testdata/util-sops/test.sops.yaml",
+		"testdata/util-sops/test.sops.json",
+	}
+	assert.DeepEqual(t, expected, files)
+}
@@ -17,13 +17,16 @@ limitations under the License.
 package values
 
 import (
+	"fmt"
 	"os"
+	"sort"
 
-	"github.com/bedag/kusible/pkg/wrapper/ejson"
 	groupsfilter "github.com/bedag/kusible/pkg/groups"
 )
 
-func New(path string, groups []string, skipEval bool, ejsonSettings ejson.Settings) (Values, error) {
+// New compiles the values found at path (either a single file or a values
+// directory). Host vars are only considered for values directories.
+func New(path string, groups []string, options Options) (Values, error) {
 	var result Values
 	var err error
 
@@ -32,10 +35,20 @@ func New(path string, groups []string, skipEval bool, ejsonSettings ejson.Settin
 		return nil, err
 	}
 
+	for _, overlay := range options.Overlays {
+		overlayStat, err := os.Stat(overlay)
+		if err != nil {
+			return nil, err
+		}
+		if !overlayStat.IsDir() || stat.Mode().IsRegular() {
+			return nil, fmt.Errorf("values overlays must be directories and require a values directory: %s", overlay)
+		}
+	}
+
 	if stat.Mode().IsRegular() {
 		// the path provided is a file, treat it as a single value
 		// file, thus loading it with ejson and spruc operator support
-		result, err = NewFile(path, skipEval, ejsonSettings)
+		result, err = newFile(path, options, groups)
 		if err != nil {
 			return nil, err
 		}
@@ -46,12 +59,13 @@ func New(path string, groups []string, skipEval bool, ejsonSettings ejson.Settin
 		// get a list of all groups in the given directory
 		dirGroups := groups
 		if len(dirGroups) <= 0 {
-			dirGroups, err = groupsfilter.SortedGroups(path, ".*", []string{})
+			dirGroups, err = groupsfilter.GroupsOfDirectories(append([]string{path}, options.Overlays...), ".*", []string{})
 			if err != nil {
 				return nil, err
 			}
+			sort.Strings(dirGroups)
 		}
-		result, err = NewDirectory(path, dirGroups, skipEval, ejsonSettings)
+		result, err = NewDirectory(path, dirGroups, options)
 		if err != nil {
 			return nil, err
 		}
@@ -53,12 +53,12 @@ func TestValues(t *testing.T) {
 	}
 
 	ejsonSettings := ejson.Settings{
-		KeyDir: "testdata/keydir",
+		KeyDirs: []string{"testdata/keydir"},
 	}
 
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
-			d, err := New("testdata/"+tc.input, []string{}, tc.skipEval, ejsonSettings)
+			d, err := New("testdata/"+tc.input, []string{}, Options{SkipEval: tc.skipEval, Ejson: ejsonSettings})
 			assert.NilError(t, err)
 			got := d.Map()
 			assert.NilError(t, err)
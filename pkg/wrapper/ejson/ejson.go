@@ -18,37 +18,40 @@ package ejson
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/Shopify/ejson"
 	log "github.com/sirupsen/logrus"
 )
 
+// EnvKeyPrefix is the prefix of environment variables holding private
+// keys, the name of the variable is the prefix followed by the public key
+// (e.g. KUSIBLE_EJSON_KEY_<public key>=<private key>)
+const EnvKeyPrefix = "KUSIBLE_EJSON_KEY_"
+
 func ReadFile(path string, settings Settings) ([]byte, error) {
-	data := []byte{}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
 
-	// No decryption requested, just read the file
+	// No decryption requested, just use the file
 	if settings.SkipDecrypt {
-		data, err := ioutil.ReadFile(path)
-		if err != nil {
-			return nil, err
-		}
 		return data, nil
 	}
 
 	// try to decrypt the file
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, err
+	decrypted, err := Decrypt(data, settings)
+	if err == nil {
+		return decrypted, nil
 	}
-	defer file.Close()
-	var outBuffer bytes.Buffer
 
-	err = ejson.Decrypt(file, &outBuffer, settings.KeyDir, settings.PrivKey)
-	if err == nil {
-		data = outBuffer.Bytes()
-		return data, nil
+	if settings.Strict {
+		return nil, fmt.Errorf("failed to decrypt ejson file %s: %s", path, err)
 	}
 
 	log.WithFields(log.Fields{
@@ -56,9 +59,61 @@ func ReadFile(path string, settings Settings) ([]byte, error) {
 		"error": err.Error(),
 	}).Warn("Failed to decrypt ejson file, continuing with encrypted data")
 
-	data, err = ioutil.ReadFile(path)
+	return data, nil
+}
+
+// Decrypt decrypts the given ejson data with the private key matching
+// the public key of the data
+func Decrypt(data []byte, settings Settings) ([]byte, error) {
+	pubkey, err := PublicKey(data)
 	if err != nil {
 		return nil, err
 	}
-	return data, nil
+
+	privkey, err := FindPrivateKey(pubkey, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var outBuffer bytes.Buffer
+	err = ejson.Decrypt(bytes.NewReader(data), &outBuffer, "", privkey)
+	if err != nil {
+		return nil, err
+	}
+	return outBuffer.Bytes(), nil
+}
+
+// FindPrivateKey returns the private key for the given public key. The
+// private key given in the settings takes precedence, followed by the
+// environment (see EnvKeyPrefix) and the key directories.
+func FindPrivateKey(pubkey string, settings Settings) (string, error) {
+	if settings.PrivKey != "" {
+		return settings.PrivKey, nil
+	}
+
+	envKey := EnvKeyPrefix + pubkey
+	if privkey := os.Getenv(envKey); privkey != "" {
+		return strings.TrimSpace(privkey), nil
+	}
+
+	keyDirs := KeyDirs(settings)
+	for _, keyDir := range keyDirs {
+		privkey, err := ioutil.ReadFile(filepath.Join(keyDir, pubkey))
+		if err == nil {
+			return strings.TrimSpace(string(privkey)), nil
+		}
+	}
+
+	return "", fmt.Errorf("no private key for public key %s found in key directories [%s] or environment variable %s", pubkey, strings.Join(keyDirs, ", "), envKey)
+}
+
+// KeyDirs returns all (non-empty) directories searched for private keys
+func KeyDirs(settings Settings) []string {
+	result := []string{}
+	for _, keyDir := range settings.KeyDirs {
+		if keyDir != "" {
+			result = append(result, keyDir)
+		}
+	}
+	return result
 }
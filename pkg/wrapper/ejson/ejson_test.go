@@ -0,0 +1,109 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ejson
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+const testPublicKey = "92af76c9ff646114ae9788366f43901200d131878d9285e372f43327b4067766"
+
+func TestReadFile(t *testing.T) {
+	encrypted, err := ioutil.ReadFile("testdata/simple.ejson")
+	assert.NilError(t, err)
+	privkey, err := ioutil.ReadFile("testdata/keydir/" + testPublicKey)
+	assert.NilError(t, err)
+
+	tests := map[string]struct {
+		settings    Settings
+		env         string
+		decrypted   bool
+		errExpected bool
+	}{
+		"key-dir":          {settings: Settings{KeyDirs: []string{"testdata/keydir"}}, decrypted: true},
+		"additional-dirs":  {settings: Settings{KeyDirs: []string{"testdata/empty", "testdata/keydir"}}, decrypted: true},
+		"env":              {settings: Settings{KeyDirs: []string{"testdata/empty"}}, env: strings.TrimSpace(string(privkey)), decrypted: true},
+		"privkey":          {settings: Settings{PrivKey: string(privkey)}, decrypted: true},
+		"missing-key":      {settings: Settings{KeyDirs: []string{"testdata/empty"}}, decrypted: false},
+		"missing-strict":   {settings: Settings{KeyDirs: []string{"testdata/empty"}, Strict: true}, errExpected: true},
+		"skip-decrypt":     {settings: Settings{KeyDirs: []string{"testdata/keydir"}, SkipDecrypt: true}, decrypted: false},
+		"skip-with-strict": {settings: Settings{KeyDirs: []string{"testdata/empty"}, SkipDecrypt: true, Strict: true}, decrypted: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			if tc.env != "" {
+				os.Setenv(EnvKeyPrefix+testPublicKey, tc.env)
+				defer os.Unsetenv(EnvKeyPrefix + testPublicKey)
+			}
+
+			result, err := ReadFile("testdata/simple.ejson", tc.settings)
+			if tc.errExpected {
+				assert.ErrorContains(t, err, testPublicKey)
+				return
+			}
+			assert.NilError(t, err)
+			if tc.decrypted {
+				assert.Assert(t, strings.Contains(string(result), "value1"))
+			} else {
+				assert.DeepEqual(t, encrypted, result)
+			}
+		})
+	}
+}
+
+func TestEncryptRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kusible-ejson")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	pub, priv, err := GenerateKeypair(dir)
+	assert.NilError(t, err)
+	stored, err := ioutil.ReadFile(filepath.Join(dir, pub))
+	assert.NilError(t, err)
+	assert.Equal(t, priv, string(stored))
+
+	plain := []byte(`{"_public_key": "` + pub + `", "secret": "value1"}`)
+	encrypted, err := Encrypt(plain)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(string(encrypted), "value1"))
+
+	settings := Settings{KeyDirs: []string{dir}, Strict: true}
+	decrypted, err := Decrypt(encrypted, settings)
+	assert.NilError(t, err)
+	assert.Equal(t, string(plain), string(decrypted))
+
+	newPub, _, err := GenerateKeypair(dir)
+	assert.NilError(t, err)
+	rotated, err := Rotate(encrypted, settings, newPub)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(rotated), newPub))
+	assert.Assert(t, !strings.Contains(string(rotated), "value1"))
+
+	decrypted, err = Decrypt(rotated, settings)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"_public_key": "`+newPub+`", "secret": "value1"}`, string(decrypted))
+
+	_, err = Rotate(encrypted, Settings{KeyDirs: []string{"testdata/empty"}}, newPub)
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ejson
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/Shopify/ejson"
+	ejsonjson "github.com/Shopify/ejson/json"
+)
+
+var publicKeyRegex = regexp.MustCompile(`("_public_key"\s*:\s*")[^"]*(")`)
+
+// GenerateKeypair generates a new ejson keypair. If keyDir is not empty,
+// the private key is stored in the key directory.
+func GenerateKeypair(keyDir string) (string, string, error) {
+	pub, priv, err := ejson.GenerateKeypair()
+	if err != nil {
+		return "", "", err
+	}
+
+	if keyDir != "" {
+		err = os.MkdirAll(keyDir, 0700)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to create key directory: %s", err)
+		}
+		err = ioutil.WriteFile(filepath.Join(keyDir, pub), []byte(priv), 0440)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to write private key: %s", err)
+		}
+	}
+	return pub, priv, nil
+}
+
+// Encrypt encrypts all not yet encrypted values of the given ejson data
+// with the public key of the data
+func Encrypt(data []byte) ([]byte, error) {
+	var outBuffer bytes.Buffer
+	_, err := ejson.Encrypt(bytes.NewReader(data), &outBuffer)
+	if err != nil {
+		return nil, err
+	}
+	return outBuffer.Bytes(), nil
+}
+
+// EncryptFile encrypts all not yet encrypted values of the given ejson file in place
+func EncryptFile(path string) error {
+	_, err := ejson.EncryptFileInPlace(path)
+	return err
+}
+
+// DecryptFile decrypts the given ejson file, failing if the file
+// cannot be decrypted
+func DecryptFile(path string, settings Settings) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decrypt(data, settings)
+}
+
+// PublicKey returns the public key of the given ejson data
+func PublicKey(data []byte) (string, error) {
+	pubkey, err := ejsonjson.ExtractPublicKey(data)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", pubkey), nil
+}
+
+// Rotate decrypts the given ejson data and encrypts it with the given public key
+func Rotate(data []byte, settings Settings, publicKey string) ([]byte, error) {
+	decrypted, err := Decrypt(data, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	rotated := publicKeyRegex.ReplaceAll(decrypted, []byte("${1}"+publicKey+"${2}"))
+	return Encrypt(rotated)
+}
+
+// RotateFile re-encrypts the given ejson file in place with the given public key
+func RotateFile(path string, settings Settings, publicKey string) error {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	rotated, err := Rotate(data, settings, publicKey)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, rotated, stat.Mode())
+}
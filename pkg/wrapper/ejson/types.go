@@ -17,7 +17,12 @@ limitations under the License.
 package ejson
 
 type Settings struct {
-	KeyDir      string
+	// KeyDirs are the directories searched for private
+	// keys (in the given order)
+	KeyDirs     []string
 	PrivKey     string
 	SkipDecrypt bool
+	// Strict fails instead of continuing with the encrypted
+	// data if a file cannot be decrypted
+	Strict bool
 }
@@ -0,0 +1,149 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/bedag/kusible/internal/archive"
+)
+
+// name of the git executable
+const gitExecutable = "git"
+
+// commitID matches full and abbreviated commit ids
+var commitID = regexp.MustCompile("^[0-9a-f]{7,40}$")
+
+/*
+Export writes the files of the given revision (anything understood by
+git rev-parse, e.g. a commit, branch or tag) to dir. Only the part of
+the repository below the given directory (which must be inside a git
+working tree) is exported, so relative paths in dir match relative
+paths in the given directory.
+*/
+func Export(directory string, rev string, dir string) error {
+	prefix, err := run(directory, "rev-parse", "--show-prefix")
+	if err != nil {
+		return err
+	}
+	prefix = strings.TrimSpace(prefix)
+	toplevel, err := run(directory, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+
+	// git archive limits the archive to the current directory when run in
+	// a subdirectory, so it is run in the toplevel directory instead
+	output, err := run(strings.TrimSpace(toplevel), "archive", "--format=tar", fmt.Sprintf("%s:%s", rev, prefix))
+	if err != nil {
+		return err
+	}
+	return archive.Untar(strings.NewReader(output), dir)
+}
+
+/*
+Fetch writes the files of the given revision (the default branch if it is
+empty) of the repository at url to dir. If path is not empty, only the
+given file or directory is written (at the same relative path).
+*/
+func Fetch(url string, rev string, path string, dir string) error {
+	clone, err := ioutil.TempDir("", "kusible-git")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(clone)
+
+	_, err = run("", "clone", "--quiet", "--bare", url, clone)
+	if err != nil {
+		return err
+	}
+
+	if rev == "" {
+		rev = "HEAD"
+	}
+	args := []string{"archive", "--format=tar", rev}
+	if path != "" {
+		args = append(args, "--", strings.Trim(path, "/"))
+	}
+	output, err := run(clone, args...)
+	if err != nil {
+		return err
+	}
+	return archive.Untar(strings.NewReader(output), dir)
+}
+
+/*
+IsImmutable returns true if the given revision of the repository at url
+is a tag or a commit id, which always refer to the same files. Branches
+(and anything else, e.g. HEAD) can change and are not immutable.
+*/
+func IsImmutable(url string, rev string) (bool, error) {
+	if rev == "" {
+		return false, nil
+	}
+
+	output, err := run("", "ls-remote", url)
+	if err != nil {
+		return false, err
+	}
+
+	tag := false
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		ref := fields[1]
+		switch {
+		case ref == "refs/heads/"+rev || (ref == rev && strings.HasPrefix(rev, "refs/heads/")):
+			// a branch named like the revision takes precedence, as a
+			// tag with the same name would be ambiguous
+			return false, nil
+		case ref == "refs/tags/"+rev || (ref == rev && strings.HasPrefix(rev, "refs/tags/")):
+			tag = true
+		}
+	}
+	if tag {
+		return true, nil
+	}
+	return commitID.MatchString(rev), nil
+}
+
+func run(directory string, args ...string) (string, error) {
+	executable, err := exec.LookPath(gitExecutable)
+	if err != nil {
+		return "", fmt.Errorf("%s executable not available", gitExecutable)
+	}
+
+	cmd := exec.Command(executable, args...)
+	cmd.Dir = directory
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
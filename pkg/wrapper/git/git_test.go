@@ -0,0 +1,154 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+// newTestRepo creates a git repository containing project/group_vars/all.yml
+// with one commit ("vars: {rev: 1}") and an uncommitted change ("vars: {rev: 2}")
+func newTestRepo(t *testing.T) string {
+	repo, err := ioutil.TempDir("", "kusible-git-repo-")
+	assert.NilError(t, err)
+
+	subdir := filepath.Join(repo, "project")
+	assert.NilError(t, os.MkdirAll(filepath.Join(subdir, "group_vars"), 0755))
+	file := filepath.Join(subdir, "group_vars", "all.yml")
+	assert.NilError(t, ioutil.WriteFile(file, []byte("vars: {rev: 1}\n"), 0644))
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial"},
+		{"tag", "v1"},
+	} {
+		_, err := run(repo, args...)
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, ioutil.WriteFile(file, []byte("vars: {rev: 2}\n"), 0644))
+	return repo
+}
+
+func TestExport(t *testing.T) {
+	if _, err := exec.LookPath(gitExecutable); err != nil {
+		t.Skip("git executable not available")
+	}
+
+	repo := newTestRepo(t)
+	defer os.RemoveAll(repo)
+	subdir := filepath.Join(repo, "project")
+
+	dir, err := ioutil.TempDir("", "kusible-git-export-")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = Export(subdir, "HEAD", dir)
+	assert.NilError(t, err)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "group_vars", "all.yml"))
+	assert.NilError(t, err)
+	assert.Equal(t, "vars: {rev: 1}\n", string(data))
+
+	err = Export(subdir, "does-not-exist", dir)
+	assert.Assert(t, err != nil)
+}
+
+func TestFetch(t *testing.T) {
+	if _, err := exec.LookPath(gitExecutable); err != nil {
+		t.Skip("git executable not available")
+	}
+
+	repo := newTestRepo(t)
+	defer os.RemoveAll(repo)
+
+	tests := map[string]struct {
+		rev  string
+		path string
+		err  bool
+	}{
+		"default-branch": {},
+		"tag":            {rev: "v1", path: "project/group_vars"},
+		"file":           {rev: "v1", path: "project/group_vars/all.yml"},
+		"unknown-rev":    {rev: "v2", err: true},
+		"unknown-path":   {path: "project/host_vars", err: true},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "kusible-git-fetch-")
+			assert.NilError(t, err)
+			defer os.RemoveAll(dir)
+
+			err = Fetch(repo, tt.rev, tt.path, dir)
+			if tt.err {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+
+			data, err := ioutil.ReadFile(filepath.Join(dir, "project", "group_vars", "all.yml"))
+			assert.NilError(t, err)
+			assert.Equal(t, "vars: {rev: 1}\n", string(data))
+		})
+	}
+}
+
+func TestIsImmutable(t *testing.T) {
+	if _, err := exec.LookPath(gitExecutable); err != nil {
+		t.Skip("git executable not available")
+	}
+
+	repo := newTestRepo(t)
+	defer os.RemoveAll(repo)
+	_, err := run(repo, "branch", "stable")
+	assert.NilError(t, err)
+	commit, err := run(repo, "rev-parse", "HEAD")
+	assert.NilError(t, err)
+	commit = strings.TrimSpace(commit)
+
+	tests := map[string]struct {
+		rev  string
+		want bool
+	}{
+		"empty":        {rev: "", want: false},
+		"head":         {rev: "HEAD", want: false},
+		"branch":       {rev: "stable", want: false},
+		"branch-ref":   {rev: "refs/heads/stable", want: false},
+		"tag":          {rev: "v1", want: true},
+		"tag-ref":      {rev: "refs/tags/v1", want: true},
+		"commit":       {rev: commit, want: true},
+		"short-commit": {rev: commit[:7], want: true},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			immutable, err := IsImmutable(repo, tt.rev)
+			assert.NilError(t, err)
+			assert.Equal(t, tt.want, immutable)
+		})
+	}
+}
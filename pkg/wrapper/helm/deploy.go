@@ -19,7 +19,11 @@ limitations under the License.
 package helm
 
 import (
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/bedag/kusible/pkg/playbook/config"
 	"github.com/sirupsen/logrus"
@@ -27,49 +31,119 @@ import (
 	"helm.sh/helm/v3/pkg/release"
 )
 
-// DeployPlay renders all charts contained in a given play to a string containing
-// kubernetes manifests
-func (h *Helm) DeployPlay(play *config.Play) ([]*release.Release, error) {
-	releases := make([]*release.Release, len(play.Charts))
-	for _, chart := range play.Charts {
-		actionConfig, err := h.ActionConfig(chart.Namespace)
-		if err != nil {
-			return releases, fmt.Errorf("failed initialize helm client: %s", err)
-		}
-		client := action.NewUpgrade(actionConfig)
-		h.getUpgradeOptions(client)
+// DeployPlay deploys all charts contained in a given play. Charts are deployed
+// after the charts they depend on, independent charts are deployed in parallel
+// (see Options.Workers). Charts other charts depend on are deployed with wait,
+// so they are ready before the charts depending on them are deployed. If
+// required is true (other plays depend on the play), all charts are deployed
+// with wait.
+func (h *Helm) DeployPlay(play *config.Play, required bool) ([]*release.Release, error) {
+	levels, err := play.ChartLevels()
+	if err != nil {
+		return nil, err
+	}
+	requiredCharts := play.Required()
 
-		for _, pr := range play.Repos {
-			if pr.Name == chart.Repo {
-				client.ChartPathOptions.RepoURL = pr.URL
+	releases := []*release.Release{}
+	for _, level := range levels {
+		levelReleases := make([]*release.Release, len(level))
+		err := runParallel(len(level), h.options.Workers, func(i int) error {
+			chart := level[i]
+			rel, err := h.deployChart(play, chart, required || requiredCharts[chart.Name])
+			levelReleases[i] = rel
+			return err
+		})
+		for _, rel := range levelReleases {
+			if rel != nil {
+				releases = append(releases, rel)
 			}
 		}
+		// do not deploy charts depending on failed charts
+		if err != nil {
+			return releases, err
+		}
+	}
+	return releases, nil
+}
+
+// deployChart deploys the given chart of the play. With wait, helm waits until
+// the resources of the release are ready (regardless of Options.Wait).
+func (h *Helm) deployChart(play *config.Play, chart *config.Chart, wait bool) (*release.Release, error) {
+	actionConfig, err := h.ActionConfig(chart.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed initialize helm client: %s", err)
+	}
+	client := action.NewUpgrade(actionConfig)
+	h.getUpgradeOptions(client)
+	if wait {
+		client.Wait = true
+	}
 
-		if client.ChartPathOptions.RepoURL == "" {
-			return releases, fmt.Errorf("no repo '%s' for chart '%s' configured in play", chart.Repo, chart.Name)
+	for _, pr := range play.Repos {
+		if pr.Name == chart.Repo {
+			client.ChartPathOptions.RepoURL = pr.URL
 		}
+	}
+
+	if client.ChartPathOptions.RepoURL == "" {
+		return nil, fmt.Errorf("no repo '%s' for chart '%s' configured in play", chart.Repo, chart.Name)
+	}
 
-		client.Install = true
-		client.Version = chart.Version
-		client.Namespace = chart.Namespace
+	client.Install = true
+	client.Version = chart.Version
+	client.Namespace = chart.Namespace
 
-		chartName := chart.Chart
-		releaseName := chart.Name
-		values := chart.Values
+	chartName := chart.Chart
+	releaseName := chart.Name
+	values := chart.Values
 
-		h.log.WithFields(logrus.Fields{
-			"chart":     chartName,
-			"release":   releaseName,
-			"namespace": chart.Namespace,
-			"version":   chart.Version,
-		}).Info("Deploying chart.")
+	h.log.WithFields(logrus.Fields{
+		"chart":     chartName,
+		"release":   releaseName,
+		"namespace": chart.Namespace,
+		"version":   chart.Version,
+	}).Info("Deploying chart.")
 
-		rel, err := h.runUpgrade([]string{releaseName, chartName}, values, client, actionConfig)
-		if err != nil {
-			return releases, fmt.Errorf("failed to deploy chart '%s' as release '%s': %s", chartName, releaseName, err)
-		}
-		releases = append(releases, rel)
+	rel, err := h.runUpgrade([]string{releaseName, chartName}, values, client, actionConfig)
+	if err != nil {
+		return rel, fmt.Errorf("failed to deploy chart '%s' as release '%s': %s", chartName, releaseName, err)
+	}
+	return rel, nil
+}
 
+// runParallel calls f for the ids 0..n-1 with at most workers calls running
+// in parallel (one at a time if workers < 1) and waits for all calls to finish.
+// The errors of all failed calls are returned combined.
+func runParallel(n int, workers int, f func(id int) error) error {
+	if workers < 1 {
+		workers = 1
 	}
-	return releases, nil
+
+	errs := make([]string, n)
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, workers)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(id int) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			if err := f(id); err != nil {
+				errs[id] = err.Error()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	result := []string{}
+	for _, err := range errs {
+		if err != "" {
+			result = append(result, err)
+		}
+	}
+	if len(result) > 0 {
+		sort.Strings(result)
+		return errors.New(strings.Join(result, "\n"))
+	}
+	return nil
 }
@@ -60,6 +60,7 @@ func NewOptions(viper *viper.Viper) Options {
 		HistoryMax:               viper.GetInt("helm-history-max"),
 		CleanupOnFail:            viper.GetBool("helm-cleanup-on-fail"),
 		KeepHistory:              viper.GetBool("helm-keep-history"),
+		Workers:                  viper.GetInt("workers"),
 	}
 }
 
@@ -60,4 +60,7 @@ type Options struct {
 	HistoryMax               int
 	CleanupOnFail            bool
 	KeepHistory              bool
+	// Workers is the maximum number of charts of a play deployed
+	// (or uninstalled) in parallel
+	Workers int
 }
@@ -17,40 +17,72 @@ limitations under the License.
 package helm
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/bedag/kusible/pkg/playbook/config"
 	"github.com/sirupsen/logrus"
 	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/storage/driver"
 )
 
+// UninstallPlay uninstalls all charts contained in a given play in the reverse
+// order of DeployPlay(): charts are uninstalled before the charts they depend on.
 func (h *Helm) UninstallPlay(play *config.Play) ([]string, error) {
+	levels, err := play.ChartLevels()
+	if err != nil {
+		return nil, err
+	}
+
 	result := []string{}
-	for _, chart := range play.Charts {
-		actionConfig, err := h.ActionConfig(chart.Namespace)
-		if err != nil {
-			return result, fmt.Errorf("failed initialize helm client: %s", err)
+	for l := len(levels) - 1; l >= 0; l-- {
+		level := levels[l]
+		levelStatus := make([]string, len(level))
+		err := runParallel(len(level), h.options.Workers, func(i int) error {
+			status, err := h.uninstallChart(level[i])
+			levelStatus[i] = status
+			return err
+		})
+		for _, status := range levelStatus {
+			if status != "" {
+				result = append(result, status)
+			}
 		}
-		client := action.NewUninstall(actionConfig)
-		h.getUninstallOptions(client)
-
-		releaseName := chart.Name
-		h.log.WithFields(logrus.Fields{
-			"release":   releaseName,
-			"namespace": chart.Namespace,
-		}).Debug("Deleting release.")
-
-		status, err := h.runUninstall(releaseName, client)
 		if err != nil {
-			return result, fmt.Errorf("failed to uninstall release '%s': %s", releaseName, err)
+			return result, err
 		}
-		result = append(result, status)
 	}
 	return result, nil
 }
 
+func (h *Helm) uninstallChart(chart *config.Chart) (string, error) {
+	actionConfig, err := h.ActionConfig(chart.Namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed initialize helm client: %s", err)
+	}
+	client := action.NewUninstall(actionConfig)
+	h.getUninstallOptions(client)
+
+	releaseName := chart.Name
+	h.log.WithFields(logrus.Fields{
+		"release":   releaseName,
+		"namespace": chart.Namespace,
+	}).Debug("Deleting release.")
+
+	status, err := h.runUninstall(releaseName, client)
+	if err != nil {
+		return "", fmt.Errorf("failed to uninstall release '%s': %s", releaseName, err)
+	}
+	return status, nil
+}
+
 func (h *Helm) runUninstall(name string, client *action.Uninstall) (string, error) {
 	res, err := client.Run(name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		// charts are uninstalled regardless of their when condition,
+		// so the release might never have been installed
+		return fmt.Sprintf("release '%s' not installed, skipped", name), nil
+	}
 	if err != nil {
 		return "", err
 	}
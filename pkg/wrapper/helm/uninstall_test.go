@@ -0,0 +1,43 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helm
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"gotest.tools/assert"
+	"helm.sh/helm/v3/pkg/action"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+func TestRunUninstallNotInstalled(t *testing.T) {
+	actionConfig := &action.Configuration{
+		Releases:   storage.Init(driver.NewMemory()),
+		KubeClient: &kubefake.PrintingKubeClient{Out: ioutil.Discard},
+		Log:        func(format string, v ...interface{}) {},
+	}
+	h := &Helm{}
+
+	// charts are uninstalled regardless of their when condition, a
+	// release that was never installed is not an error
+	status, err := h.runUninstall("loki", action.NewUninstall(actionConfig))
+	assert.NilError(t, err)
+	assert.Equal(t, "release 'loki' not installed, skipped", status)
+}
@@ -0,0 +1,118 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// name of the sops executable
+	sopsExecutable = "sops"
+	// key of the sops metadata in encrypted documents
+	metadataKey = "sops"
+)
+
+// IsSopsFile returns true if the file name marks the file as sops encrypted
+// (*.sops.yml, *.sops.yaml, *.sops.json)
+func IsSopsFile(path string) bool {
+	base := filepath.Base(path)
+	for _, ext := range []string{".sops.yml", ".sops.yaml", ".sops.json"} {
+		if strings.HasSuffix(base, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEncrypted returns true if the given (parsed) yaml/json document
+// contains sops metadata
+func IsEncrypted(doc map[string]interface{}) bool {
+	metadata, ok := doc[metadataKey].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasMac := metadata["mac"]
+	return hasMac
+}
+
+// Decrypt decrypts the given sops encrypted file with the sops executable.
+// doc is the already parsed (encrypted) content of the file. If decryption
+// is skipped or fails and strict mode is disabled, the encrypted data is
+// returned without the sops metadata (a warning is logged on failures).
+func Decrypt(path string, doc map[string]interface{}, settings Settings) (map[string]interface{}, error) {
+	if settings.SkipDecrypt {
+		delete(doc, metadataKey)
+		return doc, nil
+	}
+
+	result, err := decrypt(path, settings)
+	if err == nil {
+		return result, nil
+	}
+	if settings.Strict {
+		return nil, fmt.Errorf("failed to decrypt sops file %s: %s", path, err)
+	}
+
+	log.WithFields(log.Fields{
+		"file":  path,
+		"error": err.Error(),
+	}).Warn("Failed to decrypt sops file, continuing with encrypted data")
+	delete(doc, metadataKey)
+	return doc, nil
+}
+
+func decrypt(path string, settings Settings) (map[string]interface{}, error) {
+	executable, err := exec.LookPath(sopsExecutable)
+	if err != nil {
+		return nil, fmt.Errorf("%s executable not available", sopsExecutable)
+	}
+
+	format := "yaml"
+	if filepath.Ext(path) == ".json" {
+		format = "json"
+	}
+
+	cmd := exec.Command(executable, "--decrypt", "--input-type", format, "--output-type", format, path)
+	cmd.Env = os.Environ()
+	if settings.AgeKeyFile != "" {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("SOPS_AGE_KEY_FILE=%s", settings.AgeKeyFile))
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var result map[string]interface{}
+	err = yaml.Unmarshal(stdout.Bytes(), &result)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse decrypted data: %s", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,138 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+	"sigs.k8s.io/yaml"
+)
+
+// testdata/encrypted.sops.yaml is encrypted for the PGP test key in
+// testdata/test-key.asc (no passphrase, only used by these tests)
+const encryptedFile = "testdata/encrypted.sops.yaml"
+
+func readEncrypted(t *testing.T) map[string]interface{} {
+	raw, err := ioutil.ReadFile(encryptedFile)
+	assert.NilError(t, err)
+	var doc map[string]interface{}
+	err = yaml.Unmarshal(raw, &doc)
+	assert.NilError(t, err)
+	return doc
+}
+
+func TestIsSopsFile(t *testing.T) {
+	tests := map[string]bool{
+		"group_vars/all.sops.yaml": true,
+		"group_vars/all.sops.yml":  true,
+		"group_vars/all.sops.json": true,
+		"group_vars/all.yaml":      false,
+		"group_vars/sops.yaml":     false,
+		"group_vars/all.ejson":     false,
+	}
+
+	for path, expected := range tests {
+		t.Run(path, func(t *testing.T) {
+			assert.Equal(t, expected, IsSopsFile(path))
+		})
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := map[string]struct {
+		doc      map[string]interface{}
+		expected bool
+	}{
+		"encrypted":      {doc: readEncrypted(t), expected: true},
+		"plain":          {doc: map[string]interface{}{"key": "value"}, expected: false},
+		"sops-key-plain": {doc: map[string]interface{}{"sops": "value"}, expected: false},
+		"empty":          {doc: nil, expected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, IsEncrypted(tc.doc))
+		})
+	}
+}
+
+func TestDecrypt(t *testing.T) {
+	if _, err := exec.LookPath(sopsExecutable); err != nil {
+		t.Skip("sops executable not available")
+	}
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg executable not available")
+	}
+
+	// import the test key into an empty keyring used by sops
+	home, err := ioutil.TempDir("", "kusible-sops")
+	assert.NilError(t, err)
+	defer os.RemoveAll(home)
+	gnupgHome := os.Getenv("GNUPGHOME")
+	os.Setenv("GNUPGHOME", home)
+	defer os.Setenv("GNUPGHOME", gnupgHome)
+	err = exec.Command("gpg", "--batch", "--quiet", "--import", "testdata/test-key.asc").Run()
+	assert.NilError(t, err)
+
+	result, err := Decrypt(encryptedFile, readEncrypted(t), Settings{Strict: true})
+	assert.NilError(t, err)
+	expected := map[string]interface{}{
+		"secret": "s3cr3t",
+		"nested": map[string]interface{}{
+			"password": "hunter2",
+			"port":     float64(5432),
+		},
+		"list": []interface{}{"a", "b"},
+	}
+	assert.DeepEqual(t, expected, result)
+}
+
+func TestDecryptFailure(t *testing.T) {
+	// make sure the sops executable cannot be found
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", "")
+	defer os.Setenv("PATH", path)
+
+	tests := map[string]struct {
+		settings    Settings
+		errExpected bool
+	}{
+		"continue": {settings: Settings{}, errExpected: false},
+		"strict":   {settings: Settings{Strict: true}, errExpected: true},
+		"skip":     {settings: Settings{SkipDecrypt: true, Strict: true}, errExpected: false},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			result, err := Decrypt(encryptedFile, readEncrypted(t), tc.settings)
+			if tc.errExpected {
+				assert.Assert(t, err != nil)
+				return
+			}
+			assert.NilError(t, err)
+			// the encrypted data is used without the sops metadata
+			_, ok := result["sops"]
+			assert.Assert(t, !ok)
+			assert.Assert(t, strings.HasPrefix(result["secret"].(string), "ENC[AES256_GCM,"))
+		})
+	}
+}
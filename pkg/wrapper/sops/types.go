@@ -0,0 +1,28 @@
+/*
+Copyright © 2021 Michael Gruener
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+type Settings struct {
+	// AgeKeyFile is the age key file used for decryption. If empty,
+	// the keys configured in the environment of sops are used
+	// (SOPS_AGE_KEY_FILE, SOPS_AGE_KEY, GnuPG keyring, ...)
+	AgeKeyFile  string
+	SkipDecrypt bool
+	// Strict fails instead of continuing with the encrypted
+	// data if a file cannot be decrypted
+	Strict bool
+}